@@ -0,0 +1,223 @@
+package traefik_maintenance_warden
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxAdminBodySize limits the size of admin API request bodies
+const maxAdminBodySize = 64 * 1024
+
+// adminWindow is the JSON representation of a maintenance window
+type adminWindow struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// adminStatus is the JSON response of the admin status endpoint
+type adminStatus struct {
+	Enabled            bool         `json:"enabled"`
+	Forced             bool         `json:"forced"`
+	Active             bool         `json:"active"`
+	MaintenanceEndTime string       `json:"maintenanceEndTime,omitempty"`
+	ActiveWindow       *adminWindow `json:"activeWindow,omitempty"`
+	NextWindow         *adminWindow `json:"nextWindow,omitempty"`
+}
+
+// adminEnableRequest is the optional JSON body of the admin enable endpoint
+type adminEnableRequest struct {
+	// Until is the RFC3339 time at which maintenance is expected to end
+	Until string `json:"until,omitempty"`
+}
+
+// serveAdmin handles requests to the admin API
+func (m *MaintenanceBypass) serveAdmin(rw http.ResponseWriter, req *http.Request) {
+	if !m.isAdminAuthorized(req) {
+		m.log(LogLevelInfo, "Unauthorized admin API request for %s", req.URL.Path)
+		m.writeAdminError(rw, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	endpoint := strings.TrimPrefix(req.URL.Path, m.adminPathPrefix)
+	method := http.MethodPost
+	if endpoint == "status" {
+		method = http.MethodGet
+	}
+
+	switch endpoint {
+	case "status", "enable", "disable", "schedule":
+	default:
+		m.writeAdminError(rw, http.StatusNotFound, "unknown admin endpoint")
+		return
+	}
+
+	if req.Method != method {
+		rw.Header().Set("Allow", method)
+		m.writeAdminError(rw, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	switch endpoint {
+	case "enable":
+		m.adminEnable(rw, req)
+	case "disable":
+		m.adminDisable(rw, req)
+	case "schedule":
+		m.adminSchedule(rw, req)
+	default:
+		m.writeAdminJSON(rw, http.StatusOK, m.adminStatus())
+	}
+}
+
+// isAdminAuthorized checks the bearer token of an admin API request in constant time
+func (m *MaintenanceBypass) isAdminAuthorized(req *http.Request) bool {
+	authHeader := req.Header.Get("Authorization")
+	if !strings.HasPrefix(strings.ToLower(authHeader), "bearer ") {
+		return false
+	}
+
+	token := authHeader[7:]
+	return subtle.ConstantTimeCompare([]byte(token), []byte(m.adminSecret)) == 1
+}
+
+// adminEnable forces maintenance mode on, optionally recording when it is expected to end
+func (m *MaintenanceBypass) adminEnable(rw http.ResponseWriter, req *http.Request) {
+	var body adminEnableRequest
+	if err := decodeAdminBody(req, &body); err != nil {
+		m.writeAdminError(rw, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var until time.Time
+	if body.Until != "" {
+		var err error
+		until, err = time.Parse(time.RFC3339, body.Until)
+		if err != nil {
+			m.writeAdminError(rw, http.StatusBadRequest, fmt.Sprintf("invalid until time: %v", err))
+			return
+		}
+	}
+
+	m.stateMutex.Lock()
+	m.enabled = true
+	m.forceEnabled = true
+	if !until.IsZero() {
+		m.maintenanceEndTime = until
+	}
+	m.stateMutex.Unlock()
+
+	m.log(LogLevelInfo, "Maintenance mode enabled via admin API")
+	m.writeAdminJSON(rw, http.StatusOK, m.adminStatus())
+}
+
+// adminDisable turns maintenance mode off
+func (m *MaintenanceBypass) adminDisable(rw http.ResponseWriter, req *http.Request) {
+	m.stateMutex.Lock()
+	m.enabled = false
+	m.forceEnabled = false
+	m.stateMutex.Unlock()
+
+	m.log(LogLevelInfo, "Maintenance mode disabled via admin API")
+	m.writeAdminJSON(rw, http.StatusOK, m.adminStatus())
+}
+
+// adminSchedule replaces the maintenance schedule; an empty schedule removes it
+func (m *MaintenanceBypass) adminSchedule(rw http.ResponseWriter, req *http.Request) {
+	var config ScheduleConfig
+	if err := decodeAdminBody(req, &config); err != nil {
+		m.writeAdminError(rw, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sched, err := newSchedule(config)
+	if err != nil {
+		m.writeAdminError(rw, http.StatusBadRequest, fmt.Sprintf("invalid schedule: %v", err))
+		return
+	}
+
+	m.stateMutex.Lock()
+	m.schedule = sched
+	m.enabled = true
+	m.forceEnabled = false
+	m.stateMutex.Unlock()
+
+	m.log(LogLevelInfo, "Maintenance schedule updated via admin API")
+	m.writeAdminJSON(rw, http.StatusOK, m.adminStatus())
+}
+
+// adminStatus builds a snapshot of the current maintenance state
+func (m *MaintenanceBypass) adminStatus() adminStatus {
+	now := m.currentTime()
+
+	m.stateMutex.RLock()
+	status := adminStatus{
+		Enabled: m.enabled,
+		Forced:  m.forceEnabled,
+	}
+	sched, maintenanceEndTime := m.schedule, m.maintenanceEndTime
+	m.stateMutex.RUnlock()
+
+	status.Active = m.isMaintenanceEnabled(nil)
+
+	if !maintenanceEndTime.IsZero() {
+		status.MaintenanceEndTime = maintenanceEndTime.Format(time.RFC3339)
+	}
+
+	if sched != nil {
+		if window, active := sched.activeWindow(now); active {
+			status.ActiveWindow = newAdminWindow(window)
+		}
+		if window, ok := sched.nextWindow(now); ok {
+			status.NextWindow = newAdminWindow(window)
+		}
+	}
+
+	return status
+}
+
+// newAdminWindow converts a maintenance window to its JSON representation
+func newAdminWindow(window maintenanceWindow) *adminWindow {
+	return &adminWindow{
+		Start: window.start.Format(time.RFC3339),
+		End:   window.end.Format(time.RFC3339),
+	}
+}
+
+// decodeAdminBody decodes an optional JSON request body
+func decodeAdminBody(req *http.Request, v interface{}) error {
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxAdminBodySize))
+	if err != nil {
+		return fmt.Errorf("error reading request body: %w", err)
+	}
+
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("invalid JSON body: %w", err)
+	}
+
+	return nil
+}
+
+// writeAdminJSON writes a JSON response from the admin API
+func (m *MaintenanceBypass) writeAdminJSON(rw http.ResponseWriter, statusCode int, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	rw.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(rw).Encode(v); err != nil {
+		m.log(LogLevelError, "Error writing admin API response: %v", err)
+	}
+}
+
+// writeAdminError writes a JSON error response from the admin API
+func (m *MaintenanceBypass) writeAdminError(rw http.ResponseWriter, statusCode int, message string) {
+	m.writeAdminJSON(rw, statusCode, map[string]string{"error": message})
+}
@@ -0,0 +1,311 @@
+package traefik_maintenance_warden
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// newAdminTestMiddleware creates a middleware with the admin API enabled
+func newAdminTestMiddleware(t *testing.T, enabled bool) *MaintenanceBypass {
+	t.Helper()
+
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	cfg := &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		Enabled:            enabled,
+		AdminSecret:        "s3cret",
+	}
+
+	middleware, err := New(context.Background(), nextHandler, cfg, "admin-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	m := middleware.(*MaintenanceBypass)
+	m.now = func() time.Time { return time.Date(2025, 1, 5, 1, 0, 0, 0, time.UTC) }
+
+	return m
+}
+
+// adminRequest sends a request to the middleware and returns the recorder
+func adminRequest(m *MaintenanceBypass, method, path, secret, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "http://example.com"+path, strings.NewReader(body))
+	if secret != "" {
+		req.Header.Set("Authorization", "Bearer "+secret)
+	}
+
+	recorder := httptest.NewRecorder()
+	m.ServeHTTP(recorder, req)
+
+	return recorder
+}
+
+// TestAdminAPIAuthorization tests authentication and routing of the admin API
+func TestAdminAPIAuthorization(t *testing.T) {
+	m := newAdminTestMiddleware(t, false)
+
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		secret         string
+		expectedStatus int
+	}{
+		{"Missing secret", http.MethodGet, "/.warden/status", "", http.StatusUnauthorized},
+		{"Wrong secret", http.MethodGet, "/.warden/status", "wrong", http.StatusUnauthorized},
+		{"Valid secret", http.MethodGet, "/.warden/status", "s3cret", http.StatusOK},
+		{"Unknown endpoint", http.MethodGet, "/.warden/unknown", "s3cret", http.StatusNotFound},
+		{"Wrong method for status", http.MethodPost, "/.warden/status", "s3cret", http.StatusMethodNotAllowed},
+		{"Wrong method for enable", http.MethodGet, "/.warden/enable", "s3cret", http.StatusMethodNotAllowed},
+		{"Outside admin prefix", http.MethodGet, "/status", "", http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := adminRequest(m, tc.method, tc.path, tc.secret, "")
+			if recorder.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, recorder.Code)
+			}
+		})
+	}
+}
+
+// TestAdminAPIDisabledWithoutSecret tests that the admin prefix is not reserved without a secret
+func TestAdminAPIDisabledWithoutSecret(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		Enabled:            false,
+	}, "admin-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/.warden/status", nil)
+	middleware.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected request to pass through, got status code %d", recorder.Code)
+	}
+
+	if recorder.Header().Get("Content-Type") == "application/json" {
+		t.Errorf("Expected admin API not to handle the request")
+	}
+}
+
+// TestAdminAPIEnableDisable tests toggling maintenance mode through the admin API
+func TestAdminAPIEnableDisable(t *testing.T) {
+	m := newAdminTestMiddleware(t, false)
+
+	if code := adminRequest(m, http.MethodGet, "/", "", "").Code; code != http.StatusOK {
+		t.Fatalf("Expected status code %d before enabling, got %d", http.StatusOK, code)
+	}
+
+	recorder := adminRequest(m, http.MethodPost, "/.warden/enable", "s3cret", `{"until":"2025-01-05T03:00:00Z"}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d from enable, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+
+	var status adminStatus
+	if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Error decoding status: %v", err)
+	}
+
+	if !status.Enabled || !status.Active || !status.Forced {
+		t.Errorf("Expected enabled, active and forced status, got %+v", status)
+	}
+
+	if status.MaintenanceEndTime != "2025-01-05T03:00:00Z" {
+		t.Errorf("Expected maintenance end time to be recorded, got %q", status.MaintenanceEndTime)
+	}
+
+	recorder = adminRequest(m, http.MethodGet, "/", "", "")
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d after enabling, got %d", http.StatusServiceUnavailable, recorder.Code)
+	}
+
+	if retryAfter := recorder.Header().Get("Retry-After"); retryAfter != "7200" {
+		t.Errorf("Expected Retry-After to reflect the until time, got %q", retryAfter)
+	}
+
+	if code := adminRequest(m, http.MethodPost, "/.warden/disable", "s3cret", "").Code; code != http.StatusOK {
+		t.Fatalf("Expected status code %d from disable, got %d", http.StatusOK, code)
+	}
+
+	if code := adminRequest(m, http.MethodGet, "/", "", "").Code; code != http.StatusOK {
+		t.Errorf("Expected status code %d after disabling, got %d", http.StatusOK, code)
+	}
+}
+
+// TestAdminAPIEnableInvalidBody tests error handling of the enable endpoint
+func TestAdminAPIEnableInvalidBody(t *testing.T) {
+	m := newAdminTestMiddleware(t, false)
+
+	testCases := []struct {
+		name string
+		body string
+	}{
+		{"Invalid JSON", `{"until":`},
+		{"Invalid until time", `{"until":"soon"}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := adminRequest(m, http.MethodPost, "/.warden/enable", "s3cret", tc.body)
+			if recorder.Code != http.StatusBadRequest {
+				t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, recorder.Code)
+			}
+		})
+	}
+
+	if m.isMaintenanceEnabled(nil) {
+		t.Errorf("Expected maintenance mode to remain disabled after invalid requests")
+	}
+}
+
+// TestAdminAPISchedule tests replacing the schedule through the admin API
+func TestAdminAPISchedule(t *testing.T) {
+	m := newAdminTestMiddleware(t, false)
+
+	body := `{"windows":[{"start":"2025-01-05T02:00:00Z","end":"2025-01-05T04:00:00Z"}]}`
+	recorder := adminRequest(m, http.MethodPost, "/.warden/schedule", "s3cret", body)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d from schedule, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+
+	var status adminStatus
+	if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Error decoding status: %v", err)
+	}
+
+	if status.Active || status.NextWindow == nil || status.NextWindow.Start != "2025-01-05T02:00:00Z" {
+		t.Errorf("Expected inactive status with upcoming window, got %+v", status)
+	}
+
+	// Inside the window, maintenance is active
+	m.now = func() time.Time { return time.Date(2025, 1, 5, 3, 0, 0, 0, time.UTC) }
+	if code := adminRequest(m, http.MethodGet, "/", "", "").Code; code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d inside scheduled window, got %d", http.StatusServiceUnavailable, code)
+	}
+
+	recorder = adminRequest(m, http.MethodGet, "/.warden/status", "s3cret", "")
+	status = adminStatus{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Error decoding status: %v", err)
+	}
+
+	if !status.Active || status.ActiveWindow == nil || status.ActiveWindow.End != "2025-01-05T04:00:00Z" {
+		t.Errorf("Expected active status with active window, got %+v", status)
+	}
+
+	// An invalid schedule is rejected and leaves the current schedule in place
+	recorder = adminRequest(m, http.MethodPost, "/.warden/schedule", "s3cret", `{"windows":[{"start":"bad"}]}`)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for invalid schedule, got %d", http.StatusBadRequest, recorder.Code)
+	}
+
+	if !m.isMaintenanceEnabled(nil) {
+		t.Errorf("Expected previous schedule to remain active")
+	}
+
+	// An empty schedule removes it, so the enabled flag alone decides
+	if code := adminRequest(m, http.MethodPost, "/.warden/schedule", "s3cret", "{}").Code; code != http.StatusOK {
+		t.Fatalf("Expected status code %d from clearing the schedule, got %d", http.StatusOK, code)
+	}
+
+	m.now = func() time.Time { return time.Date(2025, 1, 6, 3, 0, 0, 0, time.UTC) }
+	if !m.isMaintenanceEnabled(nil) {
+		t.Errorf("Expected maintenance mode to be enabled without a schedule")
+	}
+}
+
+// TestAdminAPIErrors tests error handling of request bodies and responses in the admin API
+func TestAdminAPIErrors(t *testing.T) {
+	m := newAdminTestMiddleware(t, false)
+	logWriter := &testLogWriter{}
+	m.logger = log.New(logWriter, "", 0)
+	m.logLevel = LogLevelError
+
+	if code := adminRequest(m, http.MethodPost, "/.warden/schedule", "s3cret", `{"windows":`).Code; code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for an invalid schedule body, got %d", http.StatusBadRequest, code)
+	}
+
+	// A request body that cannot be read is rejected
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/.warden/enable", iotest.ErrReader(errors.New("connection reset")))
+	req.Header.Set("Authorization", "Bearer s3cret")
+	recorder := httptest.NewRecorder()
+	m.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusBadRequest || !strings.Contains(recorder.Body.String(), "error reading request body") {
+		t.Errorf("Expected status code %d for an unreadable body, got %d: %s", http.StatusBadRequest, recorder.Code, recorder.Body.String())
+	}
+
+	// Errors writing the response are logged
+	req = httptest.NewRequest(http.MethodGet, "http://example.com/.warden/status", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	m.ServeHTTP(&MockErrorResponseWriter{}, req)
+
+	if !strings.Contains(logWriter.String(), "Error writing admin API response") {
+		t.Errorf("Expected error log about writing the admin API response, got: %s", logWriter.String())
+	}
+}
+
+// TestAdminAPIConcurrentAccess tests that admin updates and requests can run concurrently
+func TestAdminAPIConcurrentAccess(t *testing.T) {
+	m := newAdminTestMiddleware(t, false)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			path := "/.warden/enable"
+			if i%2 == 0 {
+				path = "/.warden/disable"
+			}
+			adminRequest(m, http.MethodPost, path, "s3cret", "")
+		}(i)
+		go func() {
+			defer wg.Done()
+			adminRequest(m, http.MethodGet, "/", "", "")
+		}()
+	}
+	wg.Wait()
+}
+
+// TestAdminPathPrefix tests a custom admin path prefix
+func TestAdminPathPrefix(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		AdminSecret:        "s3cret",
+		AdminPathPrefix:    "/_admin",
+	}, "admin-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	recorder := adminRequest(middleware.(*MaintenanceBypass), http.MethodGet, "/_admin/status", "s3cret", "")
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected admin status response, got status code %d", recorder.Code)
+	}
+}
@@ -0,0 +1,191 @@
+package traefik_maintenance_warden
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// BypassToken is the signed payload of a bypass token. Tokens are minted by the warden command
+// and accepted by the middleware through the bypass link, the bypass cookie and the bypass token header.
+type BypassToken struct {
+	// Expires is the Unix time at which the token stops granting access
+	Expires int64 `json:"exp"`
+
+	// Subject identifies who the token was issued to, for logging
+	Subject string `json:"sub,omitempty"`
+
+	// Scope is the host the token is valid for; an empty scope is valid for any host
+	Scope string `json:"scope,omitempty"`
+}
+
+// SignBypassToken encodes and signs a bypass token with HMAC-SHA256. The token is the base64url-encoded
+// JSON payload and the base64url-encoded signature of the encoded payload, separated by a dot.
+func SignBypassToken(secret []byte, token BypassToken) (string, error) {
+	if len(secret) == 0 {
+		return "", fmt.Errorf("bypass token secret must not be empty")
+	}
+
+	// The payload only holds numbers and strings, so encoding cannot fail
+	payload, _ := json.Marshal(token)
+	encodedPayload := base64.RawURLEncoding.EncodeToString(payload)
+
+	return encodedPayload + "." + base64.RawURLEncoding.EncodeToString(bypassTokenSignature(secret, encodedPayload)), nil
+}
+
+// DecodeBypassToken decodes the payload of a bypass token without verifying its signature or expiry
+func DecodeBypassToken(tokenString string) (BypassToken, error) {
+	var token BypassToken
+
+	encodedPayload, _, found := strings.Cut(tokenString, ".")
+	if !found {
+		return token, fmt.Errorf("invalid bypass token format")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return token, fmt.Errorf("error decoding bypass token: %w", err)
+	}
+
+	if err := json.Unmarshal(payload, &token); err != nil {
+		return token, fmt.Errorf("error parsing bypass token: %w", err)
+	}
+
+	return token, nil
+}
+
+// VerifyBypassToken checks the signature and expiry of a bypass token and returns its payload
+func VerifyBypassToken(secret []byte, tokenString string, now time.Time) (BypassToken, error) {
+	encodedPayload, encodedSignature, found := strings.Cut(tokenString, ".")
+	if !found {
+		return BypassToken{}, fmt.Errorf("invalid bypass token format")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return BypassToken{}, fmt.Errorf("error decoding bypass token signature: %w", err)
+	}
+
+	// hmac.Equal compares in constant time
+	if len(secret) == 0 || !hmac.Equal(signature, bypassTokenSignature(secret, encodedPayload)) {
+		return BypassToken{}, fmt.Errorf("invalid bypass token signature")
+	}
+
+	token, err := DecodeBypassToken(tokenString)
+	if err != nil {
+		return token, err
+	}
+
+	if !now.Before(time.Unix(token.Expires, 0)) {
+		return token, fmt.Errorf("bypass token expired at %s", time.Unix(token.Expires, 0).UTC().Format(time.RFC3339))
+	}
+
+	return token, nil
+}
+
+// bypassTokenSignature computes the HMAC-SHA256 signature of an encoded bypass token payload
+func bypassTokenSignature(secret []byte, encodedPayload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(encodedPayload))
+	return mac.Sum(nil)
+}
+
+// checkBypassToken verifies a bypass token and that its scope covers the requested host
+func (m *MaintenanceBypass) checkBypassToken(tokenString string, req *http.Request) (BypassToken, error) {
+	token, err := VerifyBypassToken(m.bypassTokenSecret, tokenString, m.currentTime())
+	if err != nil {
+		return token, err
+	}
+
+	if token.Scope != "" {
+		host := requestHost(req)
+		if !strings.EqualFold(host, token.Scope) {
+			return token, fmt.Errorf("bypass token is scoped to %s, not %s", token.Scope, host)
+		}
+	}
+
+	return token, nil
+}
+
+// serveBypassLink handles a visit to the bypass link, exchanging a valid token for a bypass cookie
+// and redirecting to the site
+func (m *MaintenanceBypass) serveBypassLink(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		rw.Header().Set("Allow", "GET, HEAD")
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tokenString := req.URL.Query().Get("token")
+	token, err := m.checkBypassToken(tokenString, req)
+	if err != nil {
+		m.log(LogLevelInfo, "Rejected bypass link: %v", err)
+		rw.Header().Set("Cache-Control", "no-store")
+		http.Error(rw, "invalid or expired bypass link", http.StatusForbidden)
+		return
+	}
+
+	expires := time.Unix(token.Expires, 0)
+	http.SetCookie(rw, &http.Cookie{
+		Name:     m.bypassCookieName,
+		Value:    tokenString,
+		Path:     "/",
+		Expires:  expires,
+		MaxAge:   int(expires.Sub(m.currentTime()).Seconds()) + 1,
+		Secure:   req.TLS != nil || req.Header.Get("X-Forwarded-Proto") == "https",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	m.log(LogLevelInfo, "Issued bypass cookie to %q valid until %s", token.Subject, expires.UTC().Format(time.RFC3339))
+
+	rw.Header().Set("Cache-Control", "no-store")
+	http.Redirect(rw, req, "/", http.StatusFound)
+}
+
+// hasBypassCookie reports whether the request carries a valid, unexpired bypass cookie
+func (m *MaintenanceBypass) hasBypassCookie(req *http.Request) bool {
+	if len(m.bypassTokenSecret) == 0 {
+		return false
+	}
+
+	cookie, err := req.Cookie(m.bypassCookieName)
+	if err != nil {
+		return false
+	}
+
+	token, err := m.checkBypassToken(cookie.Value, req)
+	if err != nil {
+		m.log(LogLevelDebug, "Bypass cookie rejected: %v", err)
+		return false
+	}
+
+	m.log(LogLevelDebug, "Bypass cookie of %q accepted", token.Subject)
+	return true
+}
+
+// hasBypassTokenHeader reports whether the request carries a valid, unexpired bypass token header
+func (m *MaintenanceBypass) hasBypassTokenHeader(req *http.Request) bool {
+	if len(m.bypassTokenSecret) == 0 || m.bypassTokenHeader == "" {
+		return false
+	}
+
+	tokenString := req.Header.Get(m.bypassTokenHeader)
+	if tokenString == "" {
+		return false
+	}
+
+	token, err := m.checkBypassToken(tokenString, req)
+	if err != nil {
+		m.log(LogLevelDebug, "Bypass token rejected: %v", err)
+		return false
+	}
+
+	m.log(LogLevelDebug, "Bypass token of %q accepted", token.Subject)
+	return true
+}
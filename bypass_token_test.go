@@ -0,0 +1,252 @@
+package traefik_maintenance_warden
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestBypassToken tests signing and verification of bypass tokens
+func TestBypassToken(t *testing.T) {
+	secret := []byte("s3cret")
+	now := time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC)
+
+	token, err := SignBypassToken(secret, BypassToken{Expires: now.Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatalf("Error signing bypass token: %v", err)
+	}
+
+	if _, err := VerifyBypassToken(secret, token, now); err != nil {
+		t.Errorf("Expected a valid token, got: %v", err)
+	}
+
+	payload, _, _ := strings.Cut(token, ".")
+	forged, _ := SignBypassToken([]byte("other"), BypassToken{Expires: now.Add(time.Hour).Unix()})
+	notJSON := base64.RawURLEncoding.EncodeToString([]byte("not-json"))
+
+	testCases := []struct {
+		name  string
+		token string
+		now   time.Time
+	}{
+		{"Expired", token, now.Add(time.Hour)},
+		{"Wrong secret", forged, now},
+		{"Missing signature", payload, now},
+		{"Invalid signature encoding", payload + ".!!", now},
+		{"Tampered payload", "eyJleHAiOjk5OTk5OTk5OTl9." + strings.SplitN(token, ".", 2)[1], now},
+		{"Empty token", "", now},
+		{"Signed payload that is not JSON", notJSON + "." + base64.RawURLEncoding.EncodeToString(bypassTokenSignature(secret, notJSON)), now},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := VerifyBypassToken(secret, tc.token, tc.now); err == nil {
+				t.Errorf("Expected an error but got none")
+			}
+		})
+	}
+}
+
+// TestBypassLink tests that the bypass link issues a cookie which bypasses maintenance until it expires
+func TestBypassLink(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		Enabled:            true,
+		BypassTokenSecret:  "s3cret",
+	}, "bypass-link-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	m := middleware.(*MaintenanceBypass)
+	now := time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	token, err := SignBypassToken([]byte("s3cret"), BypassToken{Expires: now.Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatalf("Error signing bypass token: %v", err)
+	}
+
+	// An invalid link is refused
+	recorder := httptest.NewRecorder()
+	m.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://example.com/.warden/bypass?token=forged", nil))
+	if recorder.Code != http.StatusForbidden || len(recorder.Result().Cookies()) != 0 {
+		t.Errorf("Expected an invalid link to be refused, got status code %d", recorder.Code)
+	}
+
+	// Only GET and HEAD are accepted
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "http://example.com/.warden/bypass?token="+url.QueryEscape(token), nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, recorder.Code)
+	}
+
+	// A valid link sets the cookie and redirects to the site
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/.warden/bypass?token="+url.QueryEscape(token), nil)
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusFound || recorder.Header().Get("Location") != "/" {
+		t.Fatalf("Expected a redirect to the site, got status code %d", recorder.Code)
+	}
+
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Expected a single cookie, got %d", len(cookies))
+	}
+
+	cookie := cookies[0]
+	if cookie.Name != "warden_bypass" || !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("Unexpected cookie attributes: %+v", cookie)
+	}
+	if !cookie.Expires.Equal(now.Add(time.Hour)) {
+		t.Errorf("Expected cookie to expire with the token, got %v", cookie.Expires)
+	}
+
+	serve := func(cookie *http.Cookie) int {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		recorder := httptest.NewRecorder()
+		m.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	if code := serve(nil); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected maintenance page without the cookie, got status code %d", code)
+	}
+
+	if code := serve(cookie); code != http.StatusOK {
+		t.Errorf("Expected cookie to bypass maintenance, got status code %d", code)
+	}
+
+	if code := serve(&http.Cookie{Name: "warden_bypass", Value: "forged.cookie"}); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected forged cookie to be rejected, got status code %d", code)
+	}
+
+	// The cookie stops working once the token expires
+	now = now.Add(time.Hour)
+	if code := serve(cookie); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected expired cookie to be rejected, got status code %d", code)
+	}
+}
+
+// TestBypassLinkDisabled tests that the bypass link is not served without a secret
+func TestBypassLinkDisabled(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		Enabled:            true,
+	}, "bypass-link-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/.warden/bypass?token=anything", nil)
+	req.AddCookie(&http.Cookie{Name: "warden_bypass", Value: "anything"})
+	recorder := httptest.NewRecorder()
+	middleware.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusServiceUnavailable || len(recorder.Result().Cookies()) != 0 {
+		t.Errorf("Expected maintenance page without a bypass token secret, got status code %d", recorder.Code)
+	}
+}
+
+// TestBypassTokenHeader tests bypass with a signed token header, including host scoping
+func TestBypassTokenHeader(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		Enabled:            true,
+		BypassTokenSecret:  "s3cret",
+		BypassTokenHeader:  "X-Maintenance-Bypass-Token",
+	}, "bypass-token-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	m := middleware.(*MaintenanceBypass)
+	now := time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	sign := func(token BypassToken) string {
+		signed, err := SignBypassToken([]byte("s3cret"), token)
+		if err != nil {
+			t.Fatalf("Error signing bypass token: %v", err)
+		}
+		return signed
+	}
+
+	expires := now.Add(time.Hour).Unix()
+
+	testCases := []struct {
+		name           string
+		host           string
+		token          string
+		expectedStatus int
+	}{
+		{"Unscoped token", "shop.example.com", sign(BypassToken{Expires: expires}), http.StatusOK},
+		{"Token scoped to the host", "shop.example.com", sign(BypassToken{Expires: expires, Scope: "shop.example.com"}), http.StatusOK},
+		{"Token scoped to the host with a port", "Shop.Example.com:8443", sign(BypassToken{Expires: expires, Scope: "shop.example.com"}), http.StatusOK},
+		{"Token scoped to another host", "admin.example.com", sign(BypassToken{Expires: expires, Scope: "shop.example.com"}), http.StatusServiceUnavailable},
+		{"Expired token", "shop.example.com", sign(BypassToken{Expires: now.Unix()}), http.StatusServiceUnavailable},
+		{"Forged token", "shop.example.com", "eyJleHAiOjk5OTk5OTk5OTl9.c2lnbmF0dXJl", http.StatusServiceUnavailable},
+		{"No token", "shop.example.com", "", http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+			req.Host = tc.host
+			if tc.token != "" {
+				req.Header.Set("X-Maintenance-Bypass-Token", tc.token)
+			}
+
+			recorder := httptest.NewRecorder()
+			m.ServeHTTP(recorder, req)
+
+			if recorder.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, recorder.Code)
+			}
+		})
+	}
+}
+
+// TestDecodeBypassToken tests decoding tokens without verification
+func TestDecodeBypassToken(t *testing.T) {
+	if _, err := SignBypassToken(nil, BypassToken{}); err == nil {
+		t.Errorf("Expected an error for an empty secret")
+	}
+
+	signed, err := SignBypassToken([]byte("s3cret"), BypassToken{Expires: 1736042400, Subject: "qa", Scope: "shop.example.com"})
+	if err != nil {
+		t.Fatalf("Error signing bypass token: %v", err)
+	}
+
+	token, err := DecodeBypassToken(signed)
+	if err != nil || token.Expires != 1736042400 || token.Subject != "qa" || token.Scope != "shop.example.com" {
+		t.Errorf("Unexpected decoded token %+v (err=%v)", token, err)
+	}
+
+	for _, invalid := range []string{"no-dot", "!!.sig", "bm90LWpzb24.sig"} {
+		if _, err := DecodeBypassToken(invalid); err == nil {
+			t.Errorf("Expected an error decoding %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package traefik_maintenance_warden
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// AutoTriggerConfig configures automatic maintenance mode when the upstream is failing
+type AutoTriggerConfig struct {
+	// Enabled turns on automatic maintenance mode
+	Enabled bool `json:"enabled,omitempty"`
+
+	// ErrorThresholdPercent is the share of failed requests (0-100) within the window that trips maintenance mode
+	ErrorThresholdPercent int `json:"errorThresholdPercent,omitempty"`
+
+	// MinRequests is the number of requests required within the window before the error rate is evaluated
+	MinRequests int `json:"minRequests,omitempty"`
+
+	// Window is the length of the sliding window in seconds
+	Window int `json:"window,omitempty"`
+
+	// Cooldown is how long maintenance mode stays on before a probe request is let through, in seconds
+	Cooldown int `json:"cooldown,omitempty"`
+}
+
+// breakerState is the state of the circuit breaker
+type breakerState int
+
+const (
+	// breakerClosed passes requests through and tracks their outcome
+	breakerClosed breakerState = iota
+	// breakerOpen serves the maintenance page
+	breakerOpen
+	// breakerHalfOpen lets a single probe request through to test recovery
+	breakerHalfOpen
+)
+
+// String returns the name of the breaker state
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// breakerBucket counts requests and failures for one second of the sliding window
+type breakerBucket struct {
+	second   int64
+	total    int
+	failures int
+}
+
+// circuitBreaker trips maintenance mode when the upstream error rate exceeds a threshold
+type circuitBreaker struct {
+	mutex            sync.Mutex
+	thresholdPercent int
+	minRequests      int
+	cooldown         time.Duration
+	buckets          []breakerBucket
+	state            breakerState
+	openedAt         time.Time
+	probing          bool
+}
+
+// newCircuitBreaker validates the auto-trigger configuration, returning nil if it is disabled
+func newCircuitBreaker(config AutoTriggerConfig) (*circuitBreaker, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+
+	thresholdPercent := config.ErrorThresholdPercent
+	if thresholdPercent == 0 {
+		thresholdPercent = 50
+	}
+	if thresholdPercent < 1 || thresholdPercent > 100 {
+		return nil, fmt.Errorf("errorThresholdPercent must be between 1 and 100, got %d", thresholdPercent)
+	}
+
+	minRequests := config.MinRequests
+	if minRequests <= 0 {
+		minRequests = 20
+	}
+
+	window := config.Window
+	if window <= 0 {
+		window = 60
+	}
+	if window > 3600 {
+		return nil, fmt.Errorf("window must not exceed 3600 seconds, got %d", window)
+	}
+
+	cooldown := config.Cooldown
+	if cooldown <= 0 {
+		cooldown = 30
+	}
+
+	return &circuitBreaker{
+		thresholdPercent: thresholdPercent,
+		minRequests:      minRequests,
+		cooldown:         time.Duration(cooldown) * time.Second,
+		buckets:          make([]breakerBucket, window),
+	}, nil
+}
+
+// allow reports whether a request may be sent upstream. In the half-open state only a single
+// probe request is allowed, which is reported through the probe return value.
+func (b *circuitBreaker) allow(now time.Time) (allowed bool, probe bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return false, false
+		}
+		b.state = breakerHalfOpen
+		fallthrough
+	case breakerHalfOpen:
+		if b.probing {
+			return false, false
+		}
+		b.probing = true
+		return true, true
+	default:
+		return true, false
+	}
+}
+
+// record tracks the outcome of a request sent upstream and returns the resulting state
+// along with whether the state changed
+func (b *circuitBreaker) record(now time.Time, failed bool, probe bool) (breakerState, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	previous := b.state
+
+	if probe {
+		b.probing = false
+		if failed {
+			b.state = breakerOpen
+			b.openedAt = now
+		} else {
+			b.state = breakerClosed
+			b.reset()
+		}
+		return b.state, b.state != previous
+	}
+
+	// Outcomes of requests that started before the breaker opened no longer matter
+	if b.state != breakerClosed {
+		return b.state, false
+	}
+
+	second := now.Unix()
+	bucket := &b.buckets[second%int64(len(b.buckets))]
+	if bucket.second != second {
+		*bucket = breakerBucket{second: second}
+	}
+	bucket.total++
+	if failed {
+		bucket.failures++
+	}
+
+	total, failures := b.counts(second)
+	if total >= b.minRequests && failures*100 >= b.thresholdPercent*total {
+		b.state = breakerOpen
+		b.openedAt = now
+	}
+
+	return b.state, b.state != previous
+}
+
+// counts sums requests and failures within the sliding window ending at the given second
+func (b *circuitBreaker) counts(second int64) (total int, failures int) {
+	oldest := second - int64(len(b.buckets))
+	for _, bucket := range b.buckets {
+		if bucket.second > oldest && bucket.second <= second {
+			total += bucket.total
+			failures += bucket.failures
+		}
+	}
+	return total, failures
+}
+
+// reset clears the sliding window
+func (b *circuitBreaker) reset() {
+	for i := range b.buckets {
+		b.buckets[i] = breakerBucket{}
+	}
+}
+
+// retryAt returns when the breaker will next let a probe through, if it is open
+func (b *circuitBreaker) retryAt() (time.Time, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state == breakerClosed {
+		return time.Time{}, false
+	}
+	return b.openedAt.Add(b.cooldown), true
+}
+
+// statusRecorder wraps an http.ResponseWriter to capture the status code written upstream
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+
+	// onStatus is called once, as soon as the status code is known
+	onStatus func(statusCode int)
+}
+
+// setStatus records the first status code and reports it
+func (r *statusRecorder) setStatus(statusCode int) {
+	if r.statusCode != 0 {
+		return
+	}
+	r.statusCode = statusCode
+	if r.onStatus != nil {
+		r.onStatus(statusCode)
+	}
+}
+
+// WriteHeader captures the status code and passes it to the wrapped ResponseWriter
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	r.setStatus(statusCode)
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records an implicit 200 status code if none has been set
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.setStatus(http.StatusOK)
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush records an implicit 200 status code if none has been set and passes flushes
+// through to the wrapped ResponseWriter when supported
+func (r *statusRecorder) Flush() {
+	r.setStatus(http.StatusOK)
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets the next handler take over the connection, such as for WebSocket upgrades,
+// when the wrapped ResponseWriter supports it
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer %T does not support hijacking", r.ResponseWriter)
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err == nil {
+		r.setStatus(http.StatusSwitchingProtocols)
+	}
+	return conn, rw, err
+}
+
+// serveNextWithBreaker passes the request to the next handler and records its outcome.
+// The outcome is recorded as soon as the status code is known, so a long-running response
+// such as a stream or WebSocket does not hold the breaker half-open for its whole lifetime.
+// Server errors and panics from the next handler before a status is written count as failures.
+func (m *MaintenanceBypass) serveNextWithBreaker(rw http.ResponseWriter, req *http.Request, probe bool) {
+	recorded := false
+	record := func(failed bool) {
+		if recorded {
+			return
+		}
+		recorded = true
+
+		state, changed := m.circuitBreaker.record(m.currentTime(), failed, probe)
+		if changed {
+			m.log(LogLevelInfo, "Automatic maintenance circuit breaker is now %s", state)
+		}
+	}
+
+	recorder := &statusRecorder{
+		ResponseWriter: rw,
+		onStatus: func(statusCode int) {
+			record(statusCode >= http.StatusInternalServerError)
+		},
+	}
+
+	// A handler that returns without writing responds with an implicit 200
+	returned := false
+	defer func() {
+		record(!returned)
+	}()
+
+	m.next.ServeHTTP(recorder, req)
+	returned = true
+}
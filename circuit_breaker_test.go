@@ -0,0 +1,363 @@
+package traefik_maintenance_warden
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestCircuitBreaker tests the breaker state transitions
+func TestCircuitBreaker(t *testing.T) {
+	breaker, err := newCircuitBreaker(AutoTriggerConfig{
+		Enabled:               true,
+		ErrorThresholdPercent: 50,
+		MinRequests:           4,
+		Window:                10,
+		Cooldown:              30,
+	})
+	if err != nil {
+		t.Fatalf("Error creating circuit breaker: %v", err)
+	}
+
+	now := time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC)
+
+	// Failures below the minimum number of requests do not trip the breaker
+	for i := 0; i < 3; i++ {
+		if state, _ := breaker.record(now, true, false); state != breakerClosed {
+			t.Fatalf("Expected breaker to stay closed below the minimum requests, got %s", state)
+		}
+	}
+
+	// Failures outside the sliding window are forgotten
+	now = now.Add(11 * time.Second)
+	if state, _ := breaker.record(now, false, false); state != breakerClosed {
+		t.Fatalf("Expected breaker to stay closed after old failures expired, got %s", state)
+	}
+	if total, failures := breaker.counts(now.Unix()); total != 1 || failures != 0 {
+		t.Errorf("Expected 1 request and 0 failures in the window, got %d and %d", total, failures)
+	}
+
+	// Reaching the threshold trips the breaker
+	breaker.record(now, false, false)
+	breaker.record(now, true, false)
+	state, changed := breaker.record(now, true, false)
+	if state != breakerOpen || !changed {
+		t.Fatalf("Expected breaker to open at 50%% errors, got %s (changed=%t)", state, changed)
+	}
+
+	// Outcomes of requests that started before the breaker opened are ignored
+	if state, changed := breaker.record(now, false, false); state != breakerOpen || changed {
+		t.Errorf("Expected a late outcome to leave the breaker open, got %s (changed=%t)", state, changed)
+	}
+
+	if allowed, _ := breaker.allow(now.Add(29 * time.Second)); allowed {
+		t.Errorf("Expected requests to be blocked during the cooldown")
+	}
+
+	if retryAt, open := breaker.retryAt(); !open || !retryAt.Equal(now.Add(30*time.Second)) {
+		t.Errorf("Expected retry at the end of the cooldown, got %v (open=%t)", retryAt, open)
+	}
+
+	// After the cooldown a single probe is let through
+	now = now.Add(30 * time.Second)
+	allowed, probe := breaker.allow(now)
+	if !allowed || !probe {
+		t.Fatalf("Expected a probe after the cooldown, got allowed=%t probe=%t", allowed, probe)
+	}
+	if allowed, _ := breaker.allow(now); allowed {
+		t.Errorf("Expected only a single probe while half-open")
+	}
+
+	// A failed probe opens the breaker again
+	if state, _ := breaker.record(now, true, true); state != breakerOpen {
+		t.Fatalf("Expected breaker to reopen after a failed probe, got %s", state)
+	}
+
+	// A successful probe closes the breaker
+	now = now.Add(30 * time.Second)
+	if allowed, probe := breaker.allow(now); !allowed || !probe {
+		t.Fatalf("Expected a probe after the second cooldown")
+	}
+	if state, _ := breaker.record(now, false, true); state != breakerClosed {
+		t.Fatalf("Expected breaker to close after a successful probe, got %s", state)
+	}
+	if total, _ := breaker.counts(now.Unix()); total != 0 {
+		t.Errorf("Expected the window to be reset after recovery, got %d requests", total)
+	}
+}
+
+// TestCircuitBreakerConfig tests defaults and validation of the auto-trigger configuration
+func TestCircuitBreakerConfig(t *testing.T) {
+	breaker, err := newCircuitBreaker(AutoTriggerConfig{})
+	if err != nil || breaker != nil {
+		t.Errorf("Expected no breaker when disabled, got %v (err=%v)", breaker, err)
+	}
+
+	breaker, err = newCircuitBreaker(AutoTriggerConfig{Enabled: true})
+	if err != nil {
+		t.Fatalf("Error creating circuit breaker: %v", err)
+	}
+	if breaker.thresholdPercent != 50 || breaker.minRequests != 20 || len(breaker.buckets) != 60 || breaker.cooldown != 30*time.Second {
+		t.Errorf("Unexpected defaults: %+v", breaker)
+	}
+
+	for _, config := range []AutoTriggerConfig{
+		{Enabled: true, ErrorThresholdPercent: 101},
+		{Enabled: true, ErrorThresholdPercent: -5},
+		{Enabled: true, Window: 7200},
+	} {
+		if _, err := newCircuitBreaker(config); err == nil {
+			t.Errorf("Expected an error for %+v", config)
+		}
+	}
+
+	if _, err := New(context.Background(), nil, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		AutoTrigger:        AutoTriggerConfig{Enabled: true, Window: 7200},
+	}, "auto-trigger-test"); err == nil {
+		t.Errorf("Expected New to reject an invalid autoTrigger configuration")
+	}
+}
+
+// TestAutoTrigger tests that failing upstream responses trigger the maintenance page
+func TestAutoTrigger(t *testing.T) {
+	upstreamStatus := http.StatusInternalServerError
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(upstreamStatus)
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		Enabled:            false,
+		BypassHeader:       "X-Maintenance-Bypass",
+		BypassHeaderValue:  "true",
+		AutoTrigger: AutoTriggerConfig{
+			Enabled:     true,
+			MinRequests: 2,
+			Cooldown:    10,
+		},
+	}, "auto-trigger-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	m := middleware.(*MaintenanceBypass)
+	now := time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	serve := func(bypass bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+		if bypass {
+			req.Header.Set("X-Maintenance-Bypass", "true")
+		}
+		recorder := httptest.NewRecorder()
+		m.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// Upstream errors are passed through until the breaker trips
+	for i := 0; i < 2; i++ {
+		if code := serve(false).Code; code != http.StatusInternalServerError {
+			t.Fatalf("Expected upstream status code %d, got %d", http.StatusInternalServerError, code)
+		}
+	}
+
+	recorder := serve(false)
+	if recorder.Code != http.StatusServiceUnavailable || recorder.Header().Get("X-Maintenance-Mode") != "true" {
+		t.Fatalf("Expected maintenance page after the breaker tripped, got status code %d", recorder.Code)
+	}
+
+	if retryAfter := recorder.Header().Get("Retry-After"); retryAfter != "10" {
+		t.Errorf("Expected Retry-After to match the cooldown, got %q", retryAfter)
+	}
+
+	// Bypass conditions still apply while the breaker is open
+	if code := serve(true).Code; code != http.StatusInternalServerError {
+		t.Errorf("Expected bypass to reach the upstream, got status code %d", code)
+	}
+
+	// After the cooldown a successful probe restores normal operation
+	upstreamStatus = http.StatusOK
+	now = now.Add(10 * time.Second)
+	if code := serve(false).Code; code != http.StatusOK {
+		t.Fatalf("Expected probe to reach the upstream, got status code %d", code)
+	}
+
+	if code := serve(false).Code; code != http.StatusOK {
+		t.Errorf("Expected normal operation after recovery, got status code %d", code)
+	}
+}
+
+// TestAutoTriggerStreamingProbe tests that a long-running probe closes the breaker as soon as its status is known
+func TestAutoTriggerStreamingProbe(t *testing.T) {
+	flushed := make(chan struct{})
+	release := make(chan struct{})
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/events" {
+			rw.WriteHeader(http.StatusOK)
+			return
+		}
+
+		// Stream events until released, like a server-sent events connection
+		rw.Header().Set("Content-Type", "text/event-stream")
+		rw.(http.Flusher).Flush()
+		close(flushed)
+		<-release
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		AutoTrigger:        AutoTriggerConfig{Enabled: true, MinRequests: 1, Cooldown: 10},
+	}, "auto-trigger-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	m := middleware.(*MaintenanceBypass)
+	now := time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC)
+	m.circuitBreaker.record(now, true, false)
+	m.now = func() time.Time { return now.Add(10 * time.Second) }
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://example.com/events", nil))
+	}()
+
+	select {
+	case <-flushed:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected the probe to reach the upstream")
+	}
+
+	// The stream is still open, but the upstream has answered, so other requests pass through again
+	recorder := httptest.NewRecorder()
+	m.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status code %d while the probe is still streaming, got %d", http.StatusOK, recorder.Code)
+	}
+
+	close(release)
+	<-done
+}
+
+// TestAutoTriggerPanic tests that a panicking upstream counts as a failure
+func TestAutoTriggerPanic(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		panic("upstream failure")
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		AutoTrigger:        AutoTriggerConfig{Enabled: true, MinRequests: 1},
+	}, "auto-trigger-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	m := middleware.(*MaintenanceBypass)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Expected the panic to propagate")
+			}
+		}()
+		m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	}()
+
+	if _, open := m.circuitBreaker.retryAt(); !open {
+		t.Errorf("Expected the breaker to open after a panic")
+	}
+}
+
+// TestStatusRecorder tests status capture of the response recorder
+func TestStatusRecorder(t *testing.T) {
+	recorder := &statusRecorder{ResponseWriter: httptest.NewRecorder()}
+	recorder.Write([]byte("ok"))
+	recorder.WriteHeader(http.StatusInternalServerError)
+	recorder.Flush()
+
+	if recorder.statusCode != http.StatusOK {
+		t.Errorf("Expected implicit status code %d, got %d", http.StatusOK, recorder.statusCode)
+	}
+}
+
+// TestStatusRecorderFlush tests that flushing before writing records the implicit status code
+func TestStatusRecorderFlush(t *testing.T) {
+	recorder := &statusRecorder{ResponseWriter: httptest.NewRecorder()}
+	recorder.Flush()
+	recorder.WriteHeader(http.StatusInternalServerError)
+
+	if recorder.statusCode != http.StatusOK {
+		t.Errorf("Expected implicit status code %d, got %d", http.StatusOK, recorder.statusCode)
+	}
+}
+
+// hijackableRecorder is a ResponseRecorder that supports hijacking the connection
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+	conn net.Conn
+}
+
+// Hijack returns the recorder's connection
+func (r *hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return r.conn, bufio.NewReadWriter(bufio.NewReader(r.conn), bufio.NewWriter(r.conn)), nil
+}
+
+// TestStatusRecorderHijack tests that the next handler can hijack the connection through the breaker
+func TestStatusRecorderHijack(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		hijacker, ok := rw.(http.Hijacker)
+		if !ok {
+			t.Fatalf("Expected the next handler to get a hijackable ResponseWriter")
+		}
+
+		conn, _, err := hijacker.Hijack()
+		if err != nil {
+			t.Fatalf("Error hijacking connection: %v", err)
+		}
+		conn.Close()
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		AutoTrigger:        AutoTriggerConfig{Enabled: true},
+	}, "auto-trigger-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	server, client := net.Pipe()
+	defer client.Close()
+
+	middleware.ServeHTTP(&hijackableRecorder{ResponseRecorder: httptest.NewRecorder(), conn: server},
+		httptest.NewRequest(http.MethodGet, "http://example.com/ws", nil))
+
+	m := middleware.(*MaintenanceBypass)
+	if _, open := m.circuitBreaker.retryAt(); open {
+		t.Errorf("Expected a hijacked connection not to count as a failure")
+	}
+
+	recorder := &statusRecorder{ResponseWriter: httptest.NewRecorder()}
+	if _, _, err := recorder.Hijack(); err == nil {
+		t.Errorf("Expected an error hijacking a ResponseWriter without hijacking support")
+	}
+}
+
+// TestBreakerStateString tests the names of the breaker states used in log messages
+func TestBreakerStateString(t *testing.T) {
+	for state, expected := range map[breakerState]string{
+		breakerClosed:   "closed",
+		breakerOpen:     "open",
+		breakerHalfOpen: "half-open",
+	} {
+		if name := state.String(); name != expected {
+			t.Errorf("Expected state name %q, got %q", expected, name)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package traefik_maintenance_warden
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// parseCIDRs parses a list of CIDR ranges. Plain IP addresses are treated as single-address ranges.
+func parseCIDRs(entries []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(entries))
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", entry)
+			}
+
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", entry, err)
+		}
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+// containsIP reports whether any of the networks contains the IP address
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clientIP resolves the IP address of the client. X-Forwarded-For and X-Real-IP are only
+// honoured when the immediate peer is a trusted proxy, so clients cannot spoof their address.
+// X-Forwarded-For is read from right to left, skipping trusted proxies, and the first
+// untrusted address is the client.
+func (m *MaintenanceBypass) clientIP(req *http.Request) string {
+	peer := remoteIP(req)
+	if !containsIP(m.trustedProxies, net.ParseIP(peer)) {
+		return peer
+	}
+
+	if forwardedFor := req.Header.Values("X-Forwarded-For"); len(forwardedFor) > 0 {
+		hops := strings.Split(strings.Join(forwardedFor, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			ip := net.ParseIP(hop)
+			if ip == nil {
+				// An unparseable hop cannot be attributed, so stop at the last trusted address
+				break
+			}
+			if !containsIP(m.trustedProxies, ip) {
+				return hop
+			}
+			peer = hop
+		}
+		return peer
+	}
+
+	if realIP := strings.TrimSpace(req.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+
+	return peer
+}
+
+// isBypassIP reports whether the client IP is in one of the bypass ranges
+func (m *MaintenanceBypass) isBypassIP(req *http.Request) (string, bool) {
+	bypassIPs := m.bypassIPs
+	if host := m.hostFor(req); host != nil && host.bypassIPs != nil {
+		bypassIPs = host.bypassIPs
+	}
+
+	if len(bypassIPs) == 0 {
+		return "", false
+	}
+
+	ip := m.clientIP(req)
+	return ip, containsIP(bypassIPs, net.ParseIP(ip))
+}
@@ -0,0 +1,130 @@
+package traefik_maintenance_warden
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestParseCIDRs tests parsing of IP addresses and CIDR ranges
+func TestParseCIDRs(t *testing.T) {
+	networks, err := parseCIDRs([]string{"10.0.0.0/8", " 192.168.1.10 ", "2001:db8::/32", "::1", ""})
+	if err != nil {
+		t.Fatalf("Error parsing CIDRs: %v", err)
+	}
+	if len(networks) != 4 {
+		t.Fatalf("Expected 4 networks, got %d", len(networks))
+	}
+
+	for _, invalid := range []string{"10.0.0.0/33", "not-an-ip", "300.1.1.1"} {
+		if _, err := parseCIDRs([]string{invalid}); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+// TestClientIP tests client IP resolution with and without trusted proxies
+func TestClientIP(t *testing.T) {
+	trustedProxies, err := parseCIDRs([]string{"10.0.0.0/8", "fd00::/8"})
+	if err != nil {
+		t.Fatalf("Error parsing trusted proxies: %v", err)
+	}
+
+	testCases := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		realIP       string
+		expectedIP   string
+	}{
+		{"Direct client", "203.0.113.7:5000", nil, "", "203.0.113.7"},
+		{"Untrusted peer cannot spoof X-Forwarded-For", "203.0.113.7:5000", []string{"198.51.100.1"}, "", "203.0.113.7"},
+		{"Untrusted peer cannot spoof X-Real-IP", "203.0.113.7:5000", nil, "198.51.100.1", "203.0.113.7"},
+		{"Trusted proxy with X-Forwarded-For", "10.0.0.2:5000", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"Spoofed entries left of the client are ignored", "10.0.0.2:5000", []string{"192.0.2.99, 198.51.100.1, 10.0.0.3"}, "", "198.51.100.1"},
+		{"Multiple X-Forwarded-For headers", "10.0.0.2:5000", []string{"192.0.2.99", "198.51.100.1"}, "", "198.51.100.1"},
+		{"Only trusted hops", "10.0.0.2:5000", []string{"10.0.0.4, 10.0.0.3"}, "", "10.0.0.4"},
+		{"Unparseable hop", "10.0.0.2:5000", []string{"garbage, 10.0.0.3"}, "", "10.0.0.3"},
+		{"Trusted proxy with X-Real-IP", "10.0.0.2:5000", nil, "198.51.100.1", "198.51.100.1"},
+		{"Trusted proxy with invalid X-Real-IP", "10.0.0.2:5000", nil, "garbage", "10.0.0.2"},
+		{"IPv6 trusted proxy", "[fd00::1]:5000", []string{"2001:db8::42"}, "", "2001:db8::42"},
+		{"Remote address without port", "203.0.113.7", nil, "", "203.0.113.7"},
+		{"Unparseable remote address is not a trusted proxy", "pipe", []string{"198.51.100.1"}, "", "pipe"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &MaintenanceBypass{trustedProxies: trustedProxies}
+
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for _, value := range tc.forwardedFor {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if tc.realIP != "" {
+				req.Header.Set("X-Real-IP", tc.realIP)
+			}
+
+			if ip := m.clientIP(req); ip != tc.expectedIP {
+				t.Errorf("Expected client IP %s, got %s", tc.expectedIP, ip)
+			}
+		})
+	}
+}
+
+// TestBypassIPs tests that clients in the bypass ranges pass through maintenance mode
+func TestBypassIPs(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		Enabled:            true,
+		BypassIPs:          []string{"198.51.100.0/24", "2001:db8:abcd::/48"},
+		TrustedProxies:     []string{"10.0.0.1"},
+	}, "bypass-ips-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	testCases := []struct {
+		name           string
+		remoteAddr     string
+		forwardedFor   string
+		expectedStatus int
+	}{
+		{"Office IPv4 address", "198.51.100.25:4000", "", http.StatusOK},
+		{"VPN IPv6 address", "[2001:db8:abcd:12::1]:4000", "", http.StatusOK},
+		{"Other address", "203.0.113.7:4000", "", http.StatusServiceUnavailable},
+		{"Office address behind trusted proxy", "10.0.0.1:4000", "198.51.100.25", http.StatusOK},
+		{"Spoofed office address from untrusted peer", "203.0.113.7:4000", "198.51.100.25", http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			if tc.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tc.forwardedFor)
+			}
+
+			recorder := httptest.NewRecorder()
+			middleware.ServeHTTP(recorder, req)
+
+			if recorder.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, recorder.Code)
+			}
+		})
+	}
+
+	for _, cfg := range []*Config{
+		{MaintenanceContent: "maintenance", BypassIPs: []string{"198.51.100.0/99"}},
+		{MaintenanceContent: "maintenance", TrustedProxies: []string{"proxy.internal"}},
+	} {
+		if _, err := New(context.Background(), nextHandler, cfg, "bypass-ips-test"); err == nil {
+			t.Errorf("Expected an error for %+v", cfg)
+		}
+	}
+}
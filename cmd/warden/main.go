@@ -0,0 +1,178 @@
+// Command warden mints and inspects signed bypass tokens for the Maintenance Warden middleware.
+//
+// Usage:
+//
+//	warden mint [-ttl 1h] [-subject name] [-scope host] [-url https://example.com] [-path-prefix /.warden/] [-secret-file path]
+//	warden inspect [-secret-file path] <token>
+//
+// The shared secret is read from the WARDEN_BYPASS_SECRET environment variable, or from the
+// file given with -secret-file, and must match the bypassTokenSecret of the middleware.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	warden "github.com/TechAlchemistry/traefik-maintenance-warden"
+)
+
+// secretEnv is the environment variable holding the shared secret
+const secretEnv = "WARDEN_BYPASS_SECRET"
+
+// exit terminates the process with the exit code of the command, replaced in tests
+var exit = os.Exit
+
+func main() {
+	exit(run(os.Args[1:], os.Getenv, os.Stdout, os.Stderr, time.Now))
+}
+
+// run executes the command and returns its exit code
+func run(args []string, getenv func(string) string, stdout io.Writer, stderr io.Writer, now func() time.Time) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+
+	switch args[0] {
+	case "mint":
+		return mint(args[1:], getenv, stdout, stderr, now)
+	case "inspect":
+		return inspect(args[1:], getenv, stdout, stderr, now)
+	case "help", "-h", "-help", "--help":
+		usage(stdout)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+}
+
+// usage prints the command usage
+func usage(w io.Writer) {
+	fmt.Fprintf(w, `Usage:
+  warden mint [-ttl 1h] [-subject name] [-scope host] [-url https://example.com] [-path-prefix /.warden/] [-secret-file path]
+  warden inspect [-secret-file path] <token>
+
+The shared secret is read from %s unless -secret-file is given.
+`, secretEnv)
+}
+
+// readSecret returns the shared secret from the secret file, if given, or the environment
+func readSecret(secretFile string, getenv func(string) string) ([]byte, error) {
+	if secretFile == "" {
+		return []byte(getenv(secretEnv)), nil
+	}
+
+	content, err := os.ReadFile(secretFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading secret file: %w", err)
+	}
+
+	return []byte(strings.TrimSpace(string(content))), nil
+}
+
+// mint signs a new bypass token and prints it, along with a bypass link if a site URL is given
+func mint(args []string, getenv func(string) string, stdout io.Writer, stderr io.Writer, now func() time.Time) int {
+	flags := flag.NewFlagSet("mint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	ttl := flags.Duration("ttl", time.Hour, "how long the token is valid")
+	subject := flags.String("subject", "", "who the token is issued to")
+	scope := flags.String("scope", "", "host the token is valid for (default: any host)")
+	siteURL := flags.String("url", "", "site URL to print a bypass link for")
+	pathPrefix := flags.String("path-prefix", "/.warden/", "adminPathPrefix of the middleware")
+	secretFile := flags.String("secret-file", "", "file containing the shared secret")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *ttl <= 0 {
+		fmt.Fprintln(stderr, "ttl must be positive")
+		return 2
+	}
+
+	secret, err := readSecret(*secretFile, getenv)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	token, err := warden.SignBypassToken(secret, warden.BypassToken{
+		Expires: now().Add(*ttl).Unix(),
+		Subject: *subject,
+		Scope:   *scope,
+	})
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	fmt.Fprintln(stdout, token)
+
+	if *siteURL != "" {
+		prefix := "/" + strings.Trim(*pathPrefix, "/") + "/"
+		fmt.Fprintf(stdout, "%s%sbypass?token=%s\n", strings.TrimSuffix(*siteURL, "/"), prefix, token)
+	}
+
+	return 0
+}
+
+// inspect decodes a bypass token and prints its subject, scope and expiry.
+// If a secret is available the signature is checked as well.
+func inspect(args []string, getenv func(string) string, stdout io.Writer, stderr io.Writer, now func() time.Time) int {
+	flags := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	secretFile := flags.String("secret-file", "", "file containing the shared secret")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(stderr, "inspect expects exactly one token")
+		return 2
+	}
+	tokenString := flags.Arg(0)
+
+	token, err := warden.DecodeBypassToken(tokenString)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	subject := token.Subject
+	if subject == "" {
+		subject = "-"
+	}
+	scope := token.Scope
+	if scope == "" {
+		scope = "any host"
+	}
+
+	expires := time.Unix(token.Expires, 0).UTC()
+	fmt.Fprintf(stdout, "subject: %s\n", subject)
+	fmt.Fprintf(stdout, "scope:   %s\n", scope)
+	fmt.Fprintf(stdout, "expires: %s\n", expires.Format(time.RFC3339))
+
+	secret, err := readSecret(*secretFile, getenv)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	if len(secret) == 0 {
+		fmt.Fprintf(stdout, "status:  signature not checked, set %s to verify\n", secretEnv)
+		return 0
+	}
+
+	if _, err := warden.VerifyBypassToken(secret, tokenString, now()); err != nil {
+		fmt.Fprintf(stdout, "status:  invalid (%v)\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "status:  valid for %s\n", expires.Sub(now()).Round(time.Second))
+	return 0
+}
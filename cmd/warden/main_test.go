@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	warden "github.com/TechAlchemistry/traefik-maintenance-warden"
+)
+
+// runTest runs the command with a fixed clock and secret environment
+func runTest(t *testing.T, secret string, now time.Time, args ...string) (int, string, string) {
+	t.Helper()
+
+	getenv := func(key string) string {
+		if key == secretEnv {
+			return secret
+		}
+		return ""
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run(args, getenv, &stdout, &stderr, func() time.Time { return now })
+	return code, stdout.String(), stderr.String()
+}
+
+// TestMint tests that minted tokens are accepted by the middleware's verification
+func TestMint(t *testing.T) {
+	now := time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC)
+
+	code, stdout, stderr := runTest(t, "s3cret", now, "mint", "-ttl", "2h", "-subject", "qa", "-scope", "shop.example.com", "-url", "https://shop.example.com/")
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a token and a link, got %q", stdout)
+	}
+
+	token, err := warden.VerifyBypassToken([]byte("s3cret"), lines[0], now)
+	if err != nil {
+		t.Fatalf("Expected minted token to verify, got: %v", err)
+	}
+	if token.Subject != "qa" || token.Scope != "shop.example.com" || token.Expires != now.Add(2*time.Hour).Unix() {
+		t.Errorf("Unexpected token payload: %+v", token)
+	}
+
+	if expected := "https://shop.example.com/.warden/bypass?token=" + lines[0]; lines[1] != expected {
+		t.Errorf("Expected link %q, got %q", expected, lines[1])
+	}
+}
+
+// TestMintSecretFile tests reading the secret from a file
+func TestMintSecretFile(t *testing.T) {
+	now := time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC)
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0600); err != nil {
+		t.Fatalf("Error writing secret file: %v", err)
+	}
+
+	code, stdout, stderr := runTest(t, "", now, "mint", "-secret-file", secretFile)
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	if _, err := warden.VerifyBypassToken([]byte("from-file"), strings.TrimSpace(stdout), now); err != nil {
+		t.Errorf("Expected token signed with the file secret, got: %v", err)
+	}
+}
+
+// TestInspect tests decoding and verifying tokens
+func TestInspect(t *testing.T) {
+	now := time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC)
+	token, err := warden.SignBypassToken([]byte("s3cret"), warden.BypassToken{Expires: now.Add(time.Hour).Unix(), Subject: "qa"})
+	if err != nil {
+		t.Fatalf("Error signing token: %v", err)
+	}
+
+	testCases := []struct {
+		name         string
+		secret       string
+		now          time.Time
+		expectedCode int
+		expected     []string
+	}{
+		{"Valid token", "s3cret", now, 0, []string{"subject: qa", "scope:   any host", "expires: 2025-01-05T03:00:00Z", "status:  valid for 1h0m0s"}},
+		{"Without secret", "", now, 0, []string{"subject: qa", "signature not checked"}},
+		{"Wrong secret", "other", now, 1, []string{"invalid bypass token signature"}},
+		{"Expired token", "s3cret", now.Add(2 * time.Hour), 1, []string{"bypass token expired"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, stdout, _ := runTest(t, tc.secret, tc.now, "inspect", token)
+			if code != tc.expectedCode {
+				t.Errorf("Expected exit code %d, got %d", tc.expectedCode, code)
+			}
+			for _, expected := range tc.expected {
+				if !strings.Contains(stdout, expected) {
+					t.Errorf("Expected output to contain %q, got %q", expected, stdout)
+				}
+			}
+		})
+	}
+}
+
+// TestUsageErrors tests invalid invocations
+func TestUsageErrors(t *testing.T) {
+	now := time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC)
+	token, err := warden.SignBypassToken([]byte("s3cret"), warden.BypassToken{Expires: now.Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatalf("Error signing token: %v", err)
+	}
+
+	testCases := []struct {
+		name         string
+		secret       string
+		args         []string
+		expectedCode int
+	}{
+		{"No command", "s3cret", nil, 2},
+		{"Unknown command", "s3cret", []string{"revoke"}, 2},
+		{"Help", "", []string{"help"}, 0},
+		{"Missing secret", "", []string{"mint"}, 1},
+		{"Negative ttl", "s3cret", []string{"mint", "-ttl", "-1h"}, 2},
+		{"Unknown flag", "s3cret", []string{"mint", "-forever"}, 2},
+		{"Missing secret file", "", []string{"mint", "-secret-file", "/nonexistent/secret"}, 1},
+		{"Inspect without token", "s3cret", []string{"inspect"}, 2},
+		{"Inspect invalid token", "s3cret", []string{"inspect", "garbage"}, 1},
+		{"Inspect unknown flag", "s3cret", []string{"inspect", "-verbose", token}, 2},
+		{"Inspect missing secret file", "", []string{"inspect", "-secret-file", "/nonexistent/secret", token}, 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if code, _, _ := runTest(t, tc.secret, now, tc.args...); code != tc.expectedCode {
+				t.Errorf("Expected exit code %d, got %d", tc.expectedCode, code)
+			}
+		})
+	}
+}
+
+// TestMainExitCode tests that the command exits with the exit code of the command
+func TestMainExitCode(t *testing.T) {
+	args, stdout := os.Args, os.Stdout
+	defer func() {
+		os.Args, os.Stdout, exit = args, stdout, os.Exit
+	}()
+
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatalf("Error opening %s: %v", os.DevNull, err)
+	}
+	defer devNull.Close()
+
+	code := -1
+	exit = func(c int) { code = c }
+	os.Args, os.Stdout = []string{"warden", "help"}, devNull
+
+	main()
+
+	if code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+}
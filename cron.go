@@ -0,0 +1,247 @@
+package traefik_maintenance_warden
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSearchLimit bounds how far ahead the cron matcher searches for the next activation
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+// cronMacros maps the supported shorthand expressions to their five-field equivalents
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronMonthNames maps month abbreviations to their numeric values
+var cronMonthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+// cronDayNames maps weekday abbreviations to their numeric values
+var cronDayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// cronSchedule is a parsed five-field cron expression (minute hour day-of-month month day-of-week).
+// Each field is stored as a bit set of the values it matches.
+type cronSchedule struct {
+	minute     uint64
+	hour       uint64
+	dayOfMonth uint64
+	month      uint64
+	dayOfWeek  uint64
+	// anyDay is true when either day field is unrestricted, in which case both must match.
+	// Otherwise a day matches if either field matches, as in standard cron.
+	anyDay bool
+}
+
+// parseCron parses a standard five-field cron expression or one of the supported macros
+func parseCron(expr string) (*cronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields, got %d", expr, len(fields))
+	}
+
+	minute, err := parseCronField(fields[0], 0, 59, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid minute field: %w", err)
+	}
+
+	hour, err := parseCronField(fields[1], 0, 23, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hour field: %w", err)
+	}
+
+	dayOfMonth, err := parseCronField(fields[2], 1, 31, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid day-of-month field: %w", err)
+	}
+
+	month, err := parseCronField(fields[3], 1, 12, cronMonthNames)
+	if err != nil {
+		return nil, fmt.Errorf("invalid month field: %w", err)
+	}
+
+	// Day-of-week accepts 7 as an alias for Sunday
+	dayOfWeek, err := parseCronField(fields[4], 0, 7, cronDayNames)
+	if err != nil {
+		return nil, fmt.Errorf("invalid day-of-week field: %w", err)
+	}
+	if dayOfWeek&(1<<7) != 0 {
+		dayOfWeek = dayOfWeek&^(1<<7) | 1
+	}
+
+	return &cronSchedule{
+		minute:     minute,
+		hour:       hour,
+		dayOfMonth: dayOfMonth,
+		month:      month,
+		dayOfWeek:  dayOfWeek,
+		anyDay:     fields[2] == "*" || fields[4] == "*",
+	}, nil
+}
+
+// parseCronField parses a comma-separated list of values, ranges and steps into a bit set
+func parseCronField(field string, min, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			rangePart = part[:i]
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step = s
+		}
+
+		var low, high int
+		switch {
+		case rangePart == "*":
+			low, high = min, max
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = parseCronValue(bounds[0], names); err != nil {
+				return 0, err
+			}
+			if high, err = parseCronValue(bounds[1], names); err != nil {
+				return 0, err
+			}
+		default:
+			value, err := parseCronValue(rangePart, names)
+			if err != nil {
+				return 0, err
+			}
+			low, high = value, value
+			// A single value with a step, like "5/15", runs to the end of the range
+			if step > 1 {
+				high = max
+			}
+		}
+
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("value %q out of range %d-%d", part, min, max)
+		}
+
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+// parseCronValue parses a single numeric or named cron value
+func parseCronValue(value string, names map[string]int) (int, error) {
+	if n, ok := names[strings.ToLower(value)]; ok {
+		return n, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+
+	return n, nil
+}
+
+// matchesDay reports whether the day of t matches the day-of-month and day-of-week fields
+func (c *cronSchedule) matchesDay(t time.Time) bool {
+	domMatch := c.dayOfMonth&(1<<uint(t.Day())) != 0
+	dowMatch := c.dayOfWeek&(1<<uint(t.Weekday())) != 0
+
+	if c.anyDay {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// next returns the first activation strictly after the given time, evaluated in location.
+// Like standard cron, a time skipped by a daylight saving transition activates at the transition
+// and a time that occurs twice activates only at its first occurrence.
+// The zero time is returned if no activation exists within the search limit.
+func (c *cronSchedule) next(after time.Time, location *time.Location) time.Time {
+	// Wall clock times are searched in UTC, which has no daylight saving transitions,
+	// and each match is then converted to an instant in location
+	local := after.In(location)
+	t := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), 0, 0, time.UTC).Add(time.Minute)
+	limit := t.Add(cronSearchLimit)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+
+		if !c.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		// The second occurrence of an ambiguous wall time resolves to its first, which may already have passed
+		if instant := cronInstant(t, location); instant.After(after) {
+			return instant
+		}
+		t = t.Add(time.Minute)
+	}
+
+	return time.Time{}
+}
+
+// cronInstant converts a wall clock time, given in UTC, to an instant in location.
+// An ambiguous wall time resolves to its first occurrence and a wall time skipped
+// by a daylight saving transition resolves to the transition.
+func cronInstant(wall time.Time, location *time.Location) time.Time {
+	seconds := wall.Unix()
+
+	// The offsets a day before and after cover a daylight saving transition on the day
+	_, offsetBefore := time.Unix(seconds-86400, 0).In(location).Zone()
+	_, offsetAfter := time.Unix(seconds+86400, 0).In(location).Zone()
+
+	var first time.Time
+	for _, offset := range []int{offsetBefore, offsetAfter} {
+		instant := time.Unix(seconds-int64(offset), 0).In(location)
+		if _, actual := instant.Zone(); actual != offset {
+			continue
+		}
+		if first.IsZero() || instant.Before(first) {
+			first = instant
+		}
+	}
+
+	if !first.IsZero() {
+		return first
+	}
+
+	// The wall time falls into the gap of a transition. Read with the earlier offset it lies
+	// after the transition, so the zone in effect then starts at the transition.
+	start, _ := time.Unix(seconds-int64(offsetBefore), 0).In(location).ZoneBounds()
+	return start
+}
@@ -0,0 +1,177 @@
+package traefik_maintenance_warden
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseCron tests parsing of valid and invalid cron expressions
+func TestParseCron(t *testing.T) {
+	testCases := []struct {
+		name        string
+		expr        string
+		expectError bool
+	}{
+		{"Every minute", "* * * * *", false},
+		{"Weekly on Sunday", "0 2 * * 0", false},
+		{"Weekday names", "30 1 * * mon-fri", false},
+		{"Month names", "0 0 1 jan,jul *", false},
+		{"Steps", "*/15 */2 * * *", false},
+		{"Range with step", "0-30/10 8-18/2 * * *", false},
+		{"Sunday as 7", "0 2 * * 7", false},
+		{"Macro", "@weekly", false},
+		{"Too few fields", "0 2 * *", true},
+		{"Too many fields", "0 2 * * * *", true},
+		{"Minute out of range", "60 * * * *", true},
+		{"Hour out of range", "0 24 * * *", true},
+		{"Day of month zero", "0 0 0 * *", true},
+		{"Month out of range", "0 0 1 13 *", true},
+		{"Day of week out of range", "0 0 * * 8", true},
+		{"Invalid value", "a * * * *", true},
+		{"Invalid step", "*/0 * * * *", true},
+		{"Reversed range", "30-10 * * * *", true},
+		{"Single value with step", "5/15 * * * *", false},
+		{"Invalid range start", "x-10 * * * *", true},
+		{"Invalid range end", "10-x * * * *", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseCron(tc.expr)
+			if tc.expectError && err == nil {
+				t.Errorf("Expected an error for %q but got none", tc.expr)
+			} else if !tc.expectError && err != nil {
+				t.Errorf("Did not expect an error for %q but got: %v", tc.expr, err)
+			}
+		})
+	}
+}
+
+// TestCronNext tests computing the next activation of a cron expression
+func TestCronNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("Failed to load time zone: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		expr     string
+		after    time.Time
+		location *time.Location
+		expected time.Time
+	}{
+		{
+			name:     "Next minute",
+			expr:     "* * * * *",
+			after:    time.Date(2025, 1, 1, 10, 15, 30, 0, time.UTC),
+			location: time.UTC,
+			expected: time.Date(2025, 1, 1, 10, 16, 0, 0, time.UTC),
+		},
+		{
+			name:     "Strictly after",
+			expr:     "0 2 * * *",
+			after:    time.Date(2025, 1, 1, 2, 0, 0, 0, time.UTC),
+			location: time.UTC,
+			expected: time.Date(2025, 1, 2, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Weekly on Sunday in Berlin",
+			expr:     "0 2 * * sun",
+			after:    time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), // Wednesday
+			location: berlin,
+			expected: time.Date(2025, 1, 5, 2, 0, 0, 0, berlin),
+		},
+		{
+			name:     "Sunday as 7",
+			expr:     "0 2 * * 7",
+			after:    time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			location: time.UTC,
+			expected: time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Day of month or day of week",
+			expr:     "0 0 15 * mon",
+			after:    time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC), // Tuesday
+			location: time.UTC,
+			expected: time.Date(2025, 1, 13, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Month rollover",
+			expr:     "0 0 1 jul *",
+			after:    time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC),
+			location: time.UTC,
+			expected: time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Skipped time runs at the spring forward transition",
+			expr:     "30 * * * *",
+			after:    time.Date(2025, 3, 30, 1, 45, 0, 0, berlin),
+			location: berlin,
+			expected: time.Date(2025, 3, 30, 1, 0, 0, 0, time.UTC), // 03:00 CEST
+		},
+		{
+			name:     "Hourly resumes after the spring forward transition",
+			expr:     "30 * * * *",
+			after:    time.Date(2025, 3, 30, 1, 0, 0, 0, time.UTC),
+			location: berlin,
+			expected: time.Date(2025, 3, 30, 3, 30, 0, 0, berlin),
+		},
+		{
+			name:     "Weekly window on spring forward Sunday",
+			expr:     "0 2 * * 0",
+			after:    time.Date(2026, 3, 28, 12, 0, 0, 0, berlin),
+			location: berlin,
+			expected: time.Date(2026, 3, 29, 1, 0, 0, 0, time.UTC), // 03:00 CEST
+		},
+		{
+			name:     "Weekly window on fall back Sunday runs at the first occurrence",
+			expr:     "0 2 * * 0",
+			after:    time.Date(2026, 10, 24, 12, 0, 0, 0, berlin),
+			location: berlin,
+			expected: time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC), // 02:00 CEST
+		},
+		{
+			name:     "Ambiguous time later in the first occurrence",
+			expr:     "45 2 * * *",
+			after:    time.Date(2026, 10, 25, 0, 30, 0, 0, time.UTC), // 02:30 CEST
+			location: berlin,
+			expected: time.Date(2026, 10, 25, 0, 45, 0, 0, time.UTC), // 02:45 CEST
+		},
+		{
+			name:     "Ambiguous time does not run again in the second occurrence",
+			expr:     "30 2 * * *",
+			after:    time.Date(2026, 10, 25, 1, 0, 0, 0, time.UTC), // 02:00 CET
+			location: berlin,
+			expected: time.Date(2026, 10, 26, 2, 30, 0, 0, berlin),
+		},
+		{
+			name:     "Leap day",
+			expr:     "0 0 29 feb *",
+			after:    time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			location: time.UTC,
+			expected: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Never matches",
+			expr:     "0 0 31 feb *",
+			after:    time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			location: time.UTC,
+			expected: time.Time{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cron, err := parseCron(tc.expr)
+			if err != nil {
+				t.Fatalf("Error parsing cron expression: %v", err)
+			}
+
+			next := cron.next(tc.after, tc.location)
+			if !next.Equal(tc.expected) {
+				t.Errorf("Expected next activation %v, got %v", tc.expected, next)
+			}
+		})
+	}
+}
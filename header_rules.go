@@ -0,0 +1,146 @@
+package traefik_maintenance_warden
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// BypassHeaderRuleConfig configures a named bypass header rule
+type BypassHeaderRuleConfig struct {
+	// Name identifies the rule in logs, for example the team it was issued to
+	Name string `json:"name,omitempty"`
+
+	// Header is the name of the bypass header
+	Header string `json:"header,omitempty"`
+
+	// Values are the accepted plaintext header values
+	Values []string `json:"values,omitempty"`
+
+	// ValueHashes are hashes of accepted header values, in the same forms as BypassHeaderValueHash
+	ValueHashes []string `json:"valueHashes,omitempty"`
+
+	// Expires is when the rule stops being accepted, as an RFC3339 time or a date (valid through that day, UTC)
+	Expires string `json:"expires,omitempty"`
+}
+
+// bypassHeaderRule is a parsed bypass header rule
+type bypassHeaderRule struct {
+	name    string
+	header  string
+	values  []*secretMatcher
+	expires time.Time
+}
+
+// newBypassHeaderRules parses the bypass header rules. Without rules, the single bypass header
+// and value are used as a rule named after the header.
+func newBypassHeaderRules(configs []BypassHeaderRuleConfig, header string, value *secretMatcher) ([]bypassHeaderRule, error) {
+	if len(configs) == 0 {
+		if header == "" {
+			return nil, nil
+		}
+		return []bypassHeaderRule{{name: header, header: header, values: []*secretMatcher{value}}}, nil
+	}
+
+	rules := make([]bypassHeaderRule, 0, len(configs))
+	names := make(map[string]bool)
+
+	for i, config := range configs {
+		if config.Name == "" {
+			return nil, fmt.Errorf("rule %d must have a name", i)
+		}
+		if names[config.Name] {
+			return nil, fmt.Errorf("duplicate rule name %q", config.Name)
+		}
+		names[config.Name] = true
+
+		if config.Header == "" {
+			return nil, fmt.Errorf("rule %q must have a header", config.Name)
+		}
+
+		if len(config.Values) == 0 && len(config.ValueHashes) == 0 {
+			return nil, fmt.Errorf("rule %q must have at least one value or value hash", config.Name)
+		}
+
+		rule := bypassHeaderRule{name: config.Name, header: config.Header}
+
+		for _, value := range config.Values {
+			if value == "" {
+				return nil, fmt.Errorf("rule %q has an empty value", config.Name)
+			}
+			matcher, _ := newSecretMatcher(value, "")
+			rule.values = append(rule.values, matcher)
+		}
+
+		for _, hashed := range config.ValueHashes {
+			matcher, err := newSecretMatcher("", hashed)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q has an invalid value hash: %w", config.Name, err)
+			}
+			rule.values = append(rule.values, matcher)
+		}
+
+		if config.Expires != "" {
+			expires, err := parseRuleExpiry(config.Expires)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q has an invalid expiry: %w", config.Name, err)
+			}
+			rule.expires = expires
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// parseRuleExpiry parses an RFC3339 time, or a date that is valid through the end of that day in UTC
+func parseRuleExpiry(value string) (time.Time, error) {
+	if expires, err := time.Parse(time.RFC3339, value); err == nil {
+		return expires, nil
+	}
+
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected an RFC3339 time or a YYYY-MM-DD date, got %q", value)
+	}
+
+	return date.AddDate(0, 0, 1), nil
+}
+
+// matchBypassHeaderRule returns the name of the first unexpired rule whose header carries an accepted value
+func (m *MaintenanceBypass) matchBypassHeaderRule(req *http.Request) (string, bool) {
+	now := m.currentTime()
+
+	rules := m.bypassHeaderRules
+	if host := m.hostFor(req); host != nil && host.bypassHeaderRules != nil {
+		rules = host.bypassHeaderRules
+	}
+
+	for _, rule := range rules {
+		headerValue := req.Header.Get(rule.header)
+		if headerValue == "" {
+			continue
+		}
+
+		matched := false
+		for _, value := range rule.values {
+			if value.matches(headerValue) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		if !rule.expires.IsZero() && !now.Before(rule.expires) {
+			m.log(LogLevelDebug, "Bypass header rule %q expired at %s", rule.name, rule.expires.UTC().Format(time.RFC3339))
+			continue
+		}
+
+		return rule.name, true
+	}
+
+	return "", false
+}
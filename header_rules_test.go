@@ -0,0 +1,130 @@
+package traefik_maintenance_warden
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestBypassHeaderRules tests bypass with named header rules
+func TestBypassHeaderRules(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		Enabled:            true,
+		BypassHeader:       "X-Maintenance-Bypass",
+		BypassHeaderValue:  "true",
+		BypassHeaderRules: []BypassHeaderRuleConfig{
+			{Name: "qa", Header: "X-QA-Bypass", Values: []string{"qa-secret", "qa-secret-2"}},
+			{Name: "ops", Header: "X-Ops-Bypass", ValueHashes: []string{"sha256:7509900f69b7d4f018b111f107a6c7fc92e3664ccaff6ed2c397bf02f5afe725"}},
+			{Name: "contractor", Header: "X-QA-Bypass", Values: []string{"contractor-secret"}, Expires: "2025-01-05"},
+			{Name: "launch", Header: "X-Launch-Bypass", Values: []string{"launch-secret"}, Expires: "2025-01-05T01:00:00Z"},
+		},
+	}, "header-rules-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	m := middleware.(*MaintenanceBypass)
+	now := time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	testCases := []struct {
+		name         string
+		header       string
+		value        string
+		expectedRule string
+	}{
+		{"First value of a rule", "X-QA-Bypass", "qa-secret", "qa"},
+		{"Second value of a rule", "X-QA-Bypass", "qa-secret-2", "qa"},
+		{"Hashed value", "X-Ops-Bypass", "team-secret", "ops"},
+		{"Value of another rule's header", "X-Ops-Bypass", "qa-secret", ""},
+		{"Date expiry is valid through the day", "X-QA-Bypass", "contractor-secret", "contractor"},
+		{"Expired rule", "X-Launch-Bypass", "launch-secret", ""},
+		{"Single bypass header is replaced by rules", "X-Maintenance-Bypass", "true", ""},
+		{"Missing header", "", "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+
+			rule, ok := m.matchBypassHeaderRule(req)
+			if rule != tc.expectedRule || ok != (tc.expectedRule != "") {
+				t.Errorf("Expected rule %q, got %q (matched=%t)", tc.expectedRule, rule, ok)
+			}
+
+			expectedStatus := http.StatusServiceUnavailable
+			if tc.expectedRule != "" {
+				expectedStatus = http.StatusOK
+			}
+
+			recorder := httptest.NewRecorder()
+			m.ServeHTTP(recorder, req)
+			if recorder.Code != expectedStatus {
+				t.Errorf("Expected status code %d, got %d", expectedStatus, recorder.Code)
+			}
+		})
+	}
+
+	// The date expiry ends at midnight UTC
+	now = time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	req.Header.Set("X-QA-Bypass", "contractor-secret")
+	if rule, ok := m.matchBypassHeaderRule(req); ok {
+		t.Errorf("Expected rule to be expired after its date, got %q", rule)
+	}
+}
+
+// TestBypassHeaderRulesDefault tests that the single bypass header becomes a rule without configured rules
+func TestBypassHeaderRulesDefault(t *testing.T) {
+	rules, err := newBypassHeaderRules(nil, "", nil)
+	if err != nil || rules != nil {
+		t.Errorf("Expected no rules without a bypass header, got %v (err=%v)", rules, err)
+	}
+
+	value, _ := newSecretMatcher("true", "")
+	rules, err = newBypassHeaderRules(nil, "X-Maintenance-Bypass", value)
+	if err != nil || len(rules) != 1 || rules[0].name != "X-Maintenance-Bypass" {
+		t.Errorf("Expected a rule named after the bypass header, got %v (err=%v)", rules, err)
+	}
+}
+
+// TestBypassHeaderRulesValidation tests validation of the bypass header rules
+func TestBypassHeaderRulesValidation(t *testing.T) {
+	testCases := []struct {
+		name  string
+		rules []BypassHeaderRuleConfig
+	}{
+		{"Missing name", []BypassHeaderRuleConfig{{Header: "X-Bypass", Values: []string{"v"}}}},
+		{"Duplicate name", []BypassHeaderRuleConfig{
+			{Name: "qa", Header: "X-Bypass", Values: []string{"a"}},
+			{Name: "qa", Header: "X-Bypass", Values: []string{"b"}},
+		}},
+		{"Missing header", []BypassHeaderRuleConfig{{Name: "qa", Values: []string{"v"}}}},
+		{"Missing values", []BypassHeaderRuleConfig{{Name: "qa", Header: "X-Bypass"}}},
+		{"Empty value", []BypassHeaderRuleConfig{{Name: "qa", Header: "X-Bypass", Values: []string{""}}}},
+		{"Invalid hash", []BypassHeaderRuleConfig{{Name: "qa", Header: "X-Bypass", ValueHashes: []string{"sha256:zz"}}}},
+		{"Invalid expiry", []BypassHeaderRuleConfig{{Name: "qa", Header: "X-Bypass", Values: []string{"v"}, Expires: "next week"}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(context.Background(), nil, &Config{
+				MaintenanceContent: "<html><body>Maintenance</body></html>",
+				BypassHeaderRules:  tc.rules,
+			}, "header-rules-test")
+			if err == nil {
+				t.Errorf("Expected an error but got none")
+			}
+		})
+	}
+}
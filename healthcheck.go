@@ -0,0 +1,185 @@
+package traefik_maintenance_warden
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// HealthCheckConfig configures active health checking of the backend
+type HealthCheckConfig struct {
+	// URL is the health check endpoint of the backend
+	URL string `json:"url,omitempty"`
+
+	// Interval is the time between health checks in seconds
+	Interval int `json:"interval,omitempty"`
+
+	// Timeout is the timeout of a single health check in seconds
+	Timeout int `json:"timeout,omitempty"`
+
+	// ExpectedStatus is the status code of a healthy response (default: any 2xx)
+	ExpectedStatus int `json:"expectedStatus,omitempty"`
+
+	// HealthyThreshold is the number of consecutive successful checks that mark the backend healthy
+	HealthyThreshold int `json:"healthyThreshold,omitempty"`
+
+	// UnhealthyThreshold is the number of consecutive failed checks that mark the backend unhealthy
+	UnhealthyThreshold int `json:"unhealthyThreshold,omitempty"`
+}
+
+// healthChecker probes the backend and tracks whether it is healthy
+type healthChecker struct {
+	url                string
+	interval           time.Duration
+	client             *http.Client
+	expectedStatus     int
+	healthyThreshold   int
+	unhealthyThreshold int
+	mutex              sync.RWMutex
+	healthy            bool
+	successes          int
+	failures           int
+}
+
+// newHealthChecker validates the health check configuration, returning nil if no URL is configured.
+// The backend is assumed healthy until enough checks fail.
+func newHealthChecker(config HealthCheckConfig) (*healthChecker, error) {
+	if config.URL == "" {
+		return nil, nil
+	}
+
+	checkURL, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid health check URL: %w", err)
+	}
+
+	if checkURL.Scheme == "" || checkURL.Host == "" {
+		return nil, fmt.Errorf("health check URL must include scheme and host")
+	}
+
+	interval := config.Interval
+	if interval <= 0 {
+		interval = 10
+	}
+
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = 5
+	}
+
+	healthyThreshold := config.HealthyThreshold
+	if healthyThreshold <= 0 {
+		healthyThreshold = 2
+	}
+
+	unhealthyThreshold := config.UnhealthyThreshold
+	if unhealthyThreshold <= 0 {
+		unhealthyThreshold = 3
+	}
+
+	return &healthChecker{
+		url:                config.URL,
+		interval:           time.Duration(interval) * time.Second,
+		client:             &http.Client{Timeout: time.Duration(timeout) * time.Second},
+		expectedStatus:     config.ExpectedStatus,
+		healthyThreshold:   healthyThreshold,
+		unhealthyThreshold: unhealthyThreshold,
+		healthy:            true,
+	}, nil
+}
+
+// isHealthy reports whether the backend is currently considered healthy
+func (h *healthChecker) isHealthy() bool {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return h.healthy
+}
+
+// probe performs a single health check
+func (h *healthChecker) probe(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.url, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error checking backend health: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Drain the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if h.expectedStatus != 0 && resp.StatusCode != h.expectedStatus {
+		return fmt.Errorf("unexpected status code %d, expected %d", resp.StatusCode, h.expectedStatus)
+	}
+
+	if h.expectedStatus == 0 && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// record tracks the result of a health check and returns the resulting health
+// along with whether it changed
+func (h *healthChecker) record(success bool) (healthy bool, changed bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if success {
+		h.successes++
+		h.failures = 0
+		if !h.healthy && h.successes >= h.healthyThreshold {
+			h.healthy = true
+			return true, true
+		}
+	} else {
+		h.failures++
+		h.successes = 0
+		if h.healthy && h.failures >= h.unhealthyThreshold {
+			h.healthy = false
+			return false, true
+		}
+	}
+
+	return h.healthy, false
+}
+
+// checkHealth probes the backend once and records the result
+func (m *MaintenanceBypass) checkHealth(ctx context.Context) {
+	err := m.healthChecker.probe(ctx)
+	if err != nil {
+		m.log(LogLevelDebug, "Health check failed: %v", err)
+	}
+
+	if healthy, changed := m.healthChecker.record(err == nil); changed {
+		if healthy {
+			m.log(LogLevelInfo, "Backend is healthy again, leaving automatic maintenance mode")
+		} else {
+			m.log(LogLevelError, "Backend is unhealthy, entering automatic maintenance mode: %v", err)
+		}
+	}
+}
+
+// watchHealth checks the backend health until the context is cancelled
+func (m *MaintenanceBypass) watchHealth(ctx context.Context) {
+	ticker := time.NewTicker(m.healthChecker.interval)
+	defer ticker.Stop()
+
+	for {
+		m.checkHealth(ctx)
+
+		select {
+		case <-ctx.Done():
+			m.log(LogLevelDebug, "Stopping health checks")
+			return
+		case <-ticker.C:
+		}
+	}
+}
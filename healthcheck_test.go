@@ -0,0 +1,234 @@
+package traefik_maintenance_warden
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestHealthCheckConfig tests defaults and validation of the health check configuration
+func TestHealthCheckConfig(t *testing.T) {
+	checker, err := newHealthChecker(HealthCheckConfig{})
+	if err != nil || checker != nil {
+		t.Errorf("Expected no health checker without a URL, got %v (err=%v)", checker, err)
+	}
+
+	checker, err = newHealthChecker(HealthCheckConfig{URL: "http://backend/health"})
+	if err != nil {
+		t.Fatalf("Error creating health checker: %v", err)
+	}
+
+	if checker.interval != 10*time.Second || checker.client.Timeout != 5*time.Second ||
+		checker.healthyThreshold != 2 || checker.unhealthyThreshold != 3 || !checker.healthy {
+		t.Errorf("Unexpected defaults: %+v", checker)
+	}
+
+	for _, checkURL := range []string{"://invalid", "backend/health"} {
+		if _, err := newHealthChecker(HealthCheckConfig{URL: checkURL}); err == nil {
+			t.Errorf("Expected an error for health check URL %q", checkURL)
+		}
+	}
+
+	if _, err := New(context.Background(), nil, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		HealthCheck:        HealthCheckConfig{URL: "backend/health"},
+	}, "health-check-test"); err == nil {
+		t.Errorf("Expected New to reject an invalid healthCheck configuration")
+	}
+}
+
+// TestHealthCheckThresholds tests that health only changes after consecutive results
+func TestHealthCheckThresholds(t *testing.T) {
+	var status int32 = http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer server.Close()
+
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		Enabled:            false,
+	}, "health-check-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	m := middleware.(*MaintenanceBypass)
+	m.healthChecker, err = newHealthChecker(HealthCheckConfig{
+		URL:                server.URL,
+		ExpectedStatus:     http.StatusOK,
+		HealthyThreshold:   2,
+		UnhealthyThreshold: 2,
+	})
+	if err != nil {
+		t.Fatalf("Error creating health checker: %v", err)
+	}
+
+	serve := func() int {
+		recorder := httptest.NewRecorder()
+		m.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+		return recorder.Code
+	}
+
+	m.checkHealth(context.Background())
+	if code := serve(); code != http.StatusOK {
+		t.Fatalf("Expected status code %d while healthy, got %d", http.StatusOK, code)
+	}
+
+	// A single failure is tolerated
+	atomic.StoreInt32(&status, http.StatusServiceUnavailable)
+	m.checkHealth(context.Background())
+	if code := serve(); code != http.StatusOK {
+		t.Errorf("Expected status code %d after a single failure, got %d", http.StatusOK, code)
+	}
+
+	// Consecutive failures turn maintenance mode on
+	m.checkHealth(context.Background())
+	if code := serve(); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d while unhealthy, got %d", http.StatusServiceUnavailable, code)
+	}
+
+	// An unexpected but successful status still counts as a failure
+	atomic.StoreInt32(&status, http.StatusNoContent)
+	m.checkHealth(context.Background())
+	if m.healthChecker.isHealthy() {
+		t.Errorf("Expected status %d to count as a failure", http.StatusNoContent)
+	}
+
+	// Consecutive successes restore normal operation
+	atomic.StoreInt32(&status, http.StatusOK)
+	m.checkHealth(context.Background())
+	if code := serve(); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d after a single success, got %d", http.StatusServiceUnavailable, code)
+	}
+
+	m.checkHealth(context.Background())
+	if code := serve(); code != http.StatusOK {
+		t.Errorf("Expected status code %d after recovery, got %d", http.StatusOK, code)
+	}
+}
+
+// TestHealthCheckProbe tests status code matching and connection errors
+func TestHealthCheckProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	checker, err := newHealthChecker(HealthCheckConfig{URL: server.URL})
+	if err != nil {
+		t.Fatalf("Error creating health checker: %v", err)
+	}
+
+	// Any 2xx status is healthy by default
+	if err := checker.probe(context.Background()); err != nil {
+		t.Errorf("Expected 204 to be healthy by default, got %v", err)
+	}
+
+	checker.client.Transport = &MockTransportWithError{}
+	if err := checker.probe(context.Background()); err == nil {
+		t.Errorf("Expected an error for a connection failure")
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	checker, err = newHealthChecker(HealthCheckConfig{URL: failing.URL})
+	if err != nil {
+		t.Fatalf("Error creating health checker: %v", err)
+	}
+
+	if err := checker.probe(context.Background()); err == nil {
+		t.Errorf("Expected 500 to be unhealthy by default")
+	}
+
+	checker.url = "http://backend/\x7f"
+	if err := checker.probe(context.Background()); err == nil {
+		t.Errorf("Expected an error for an invalid request URL")
+	}
+}
+
+// TestHealthCheckInterval tests that the backend is checked again on every interval
+func TestHealthCheckInterval(t *testing.T) {
+	var probes int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&probes, 1)
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	checker, err := newHealthChecker(HealthCheckConfig{URL: server.URL})
+	if err != nil {
+		t.Fatalf("Error creating health checker: %v", err)
+	}
+	checker.interval = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := &MaintenanceBypass{healthChecker: checker}
+	go m.watchHealth(ctx)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&probes) < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if count := atomic.LoadInt32(&probes); count < 3 {
+		t.Errorf("Expected repeated health checks, got %d", count)
+	}
+}
+
+// TestHealthCheckStopsWithContext tests that the health checks started by New stop when the context is cancelled
+func TestHealthCheckStopsWithContext(t *testing.T) {
+	var probes int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&probes, 1)
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := New(ctx, nextHandler, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		HealthCheck:        HealthCheckConfig{URL: server.URL, Interval: 1},
+	}, "health-check-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	// The first check happens immediately and the next one after the interval
+	deadline := time.Now().Add(3 * time.Second)
+	for atomic.LoadInt32(&probes) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if count := atomic.LoadInt32(&probes); count < 2 {
+		t.Fatalf("Expected the health checks started by New to repeat, got %d checks", count)
+	}
+
+	cancel()
+
+	// Let a check that was already running finish, then no further checks may start
+	time.Sleep(100 * time.Millisecond)
+	stopped := atomic.LoadInt32(&probes)
+	time.Sleep(1500 * time.Millisecond)
+
+	if count := atomic.LoadInt32(&probes); count != stopped {
+		t.Errorf("Expected health checks to stop when the context is cancelled, got %d more checks", count-stopped)
+	}
+}
@@ -0,0 +1,205 @@
+package traefik_maintenance_warden
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// HostConfig configures maintenance mode for requests to a host pattern.
+// Settings left empty fall back to the top-level configuration.
+type HostConfig struct {
+	// Enabled controls whether maintenance mode is active for the host, replacing the top-level enabled flag
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// StatusCode is the HTTP status code returned for the host
+	StatusCode int `json:"statusCode,omitempty"`
+
+	// MaintenanceService is the URL of the maintenance service for the host
+	MaintenanceService string `json:"maintenanceService,omitempty"`
+
+	// MaintenanceFilePath is the path to a static file served for the host
+	MaintenanceFilePath string `json:"maintenanceFilePath,omitempty"`
+
+	// MaintenanceContent is the direct content served for the host
+	MaintenanceContent string `json:"maintenanceContent,omitempty"`
+
+	// ContentType is the content type of the host's maintenance file or content
+	ContentType string `json:"contentType,omitempty"`
+
+	// BypassHeader is the header name that allows bypassing maintenance mode on the host
+	BypassHeader string `json:"bypassHeader,omitempty"`
+
+	// BypassHeaderValue is the expected value of the host's bypass header
+	BypassHeaderValue string `json:"bypassHeaderValue,omitempty"`
+
+	// BypassHeaderValueHash is a hash of the expected value of the host's bypass header
+	BypassHeaderValueHash string `json:"bypassHeaderValueHash,omitempty"`
+
+	// BypassHeaderRules are named bypass header rules for the host
+	BypassHeaderRules []BypassHeaderRuleConfig `json:"bypassHeaderRules,omitempty"`
+
+	// BypassPaths are paths on the host that bypass maintenance mode
+	BypassPaths []string `json:"bypassPaths,omitempty"`
+
+	// BypassPathRules are path rules on the host that bypass maintenance mode
+	BypassPathRules []PathRuleConfig `json:"bypassPathRules,omitempty"`
+
+	// BypassIPs are client IP addresses or CIDR ranges that bypass maintenance mode on the host
+	BypassIPs []string `json:"bypassIPs,omitempty"`
+}
+
+// hostMaintenance is the parsed configuration of a host pattern.
+// Nil bypass settings fall back to the top-level settings.
+type hostMaintenance struct {
+	maintenanceSource
+	pattern           string
+	enabled           *bool
+	bypassHeaderRules []bypassHeaderRule
+	bypassPaths       []string
+	bypassPathRules   []pathRule
+	bypassIPs         []*net.IPNet
+}
+
+// hostMatcher finds the configuration of a request host.
+// Exact host names take precedence over wildcards.
+type hostMatcher struct {
+	exact     map[string]*hostMaintenance
+	wildcards map[string]*hostMaintenance
+}
+
+// newHostMatcher parses the host map, returning nil if no hosts are configured
+func newHostMatcher(configs map[string]HostConfig) (*hostMatcher, error) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+
+	matcher := &hostMatcher{
+		exact:     make(map[string]*hostMaintenance),
+		wildcards: make(map[string]*hostMaintenance),
+	}
+
+	for pattern, config := range configs {
+		normalized := strings.ToLower(strings.TrimSpace(pattern))
+		if err := validateHostPattern(normalized); err != nil {
+			return nil, err
+		}
+
+		host, err := newHostMaintenance(normalized, config)
+		if err != nil {
+			return nil, fmt.Errorf("host %q: %w", pattern, err)
+		}
+
+		// Wildcards are keyed by the parent domain they cover, including its leading dot
+		hosts, key := matcher.exact, normalized
+		if strings.HasPrefix(normalized, "*.") {
+			hosts, key = matcher.wildcards, normalized[1:]
+		}
+
+		if _, ok := hosts[key]; ok {
+			return nil, fmt.Errorf("duplicate host %q", pattern)
+		}
+		hosts[key] = host
+	}
+
+	return matcher, nil
+}
+
+// validateHostPattern checks that a host pattern is a host name, optionally starting with a *. wildcard label
+func validateHostPattern(pattern string) error {
+	name := strings.TrimPrefix(pattern, "*.")
+	if name == "" {
+		return fmt.Errorf("host pattern %q must not be empty", pattern)
+	}
+	if strings.ContainsAny(name, "*/: ") {
+		return fmt.Errorf("host pattern %q must be a host name, optionally starting with *.", pattern)
+	}
+	return nil
+}
+
+// newHostMaintenance parses the configuration of a host pattern
+func newHostMaintenance(pattern string, config HostConfig) (*hostMaintenance, error) {
+	source, err := newMaintenanceSource(config.StatusCode, config.ContentType,
+		config.MaintenanceContent, config.MaintenanceFilePath, config.MaintenanceService)
+	if err != nil {
+		return nil, err
+	}
+
+	host := &hostMaintenance{maintenanceSource: source, pattern: pattern, enabled: config.Enabled}
+
+	// Bypass header settings of the host replace the top-level header and rules
+	if config.BypassHeader != "" || len(config.BypassHeaderRules) > 0 {
+		if len(config.BypassHeaderRules) == 0 && config.BypassHeaderValue == "" && config.BypassHeaderValueHash == "" {
+			return nil, fmt.Errorf("bypassHeader requires bypassHeaderValue or bypassHeaderValueHash")
+		}
+
+		value, err := newSecretMatcher(config.BypassHeaderValue, config.BypassHeaderValueHash)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bypassHeaderValueHash: %w", err)
+		}
+
+		host.bypassHeaderRules, err = newBypassHeaderRules(config.BypassHeaderRules, config.BypassHeader, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bypassHeaderRules: %w", err)
+		}
+	}
+
+	if len(config.BypassPaths) > 0 {
+		host.bypassPaths = config.BypassPaths
+	}
+
+	if len(config.BypassPathRules) > 0 {
+		host.bypassPathRules, err = newPathRules(config.BypassPathRules)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bypassPathRules: %w", err)
+		}
+	}
+
+	if len(config.BypassIPs) > 0 {
+		host.bypassIPs, err = parseCIDRs(config.BypassIPs)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bypassIPs: %w", err)
+		}
+	}
+
+	return host, nil
+}
+
+// match returns the configuration of a host name, or nil if no pattern matches.
+// A wildcard matches exactly one label, so *.example.com matches a.example.com but not a.b.example.com.
+func (h *hostMatcher) match(host string) *hostMaintenance {
+	if h == nil {
+		return nil
+	}
+
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	if config, ok := h.exact[host]; ok {
+		return config
+	}
+
+	dot := strings.Index(host, ".")
+	if dot <= 0 {
+		return nil
+	}
+
+	return h.wildcards[host[dot:]]
+}
+
+// requestHost returns the host of a request without its port
+func requestHost(req *http.Request) string {
+	host := req.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return host
+}
+
+// hostFor returns the configuration of the requested host, or nil if it falls back to the top-level configuration
+func (m *MaintenanceBypass) hostFor(req *http.Request) *hostMaintenance {
+	if m.hosts == nil || req == nil {
+		return nil
+	}
+	return m.hosts.match(requestHost(req))
+}
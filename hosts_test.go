@@ -0,0 +1,224 @@
+package traefik_maintenance_warden
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestHostMatcher tests exact and wildcard host matching
+func TestHostMatcher(t *testing.T) {
+	matcher, err := newHostMatcher(map[string]HostConfig{
+		"shop.example.com":       {},
+		"*.tenant.example.com":   {},
+		"VIP.Tenant.Example.com": {},
+	})
+	if err != nil {
+		t.Fatalf("Error creating host matcher: %v", err)
+	}
+
+	testCases := []struct {
+		host     string
+		expected string
+	}{
+		{"shop.example.com", "shop.example.com"},
+		{"SHOP.example.com.", "shop.example.com"},
+		{"acme.tenant.example.com", "*.tenant.example.com"},
+		{"vip.tenant.example.com", "vip.tenant.example.com"},
+		{"a.b.tenant.example.com", ""},
+		{"tenant.example.com", ""},
+		{"other.example.com", ""},
+		{"localhost", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.host, func(t *testing.T) {
+			pattern := ""
+			if host := matcher.match(tc.host); host != nil {
+				pattern = host.pattern
+			}
+
+			if pattern != tc.expected {
+				t.Errorf("Expected host %s to match %q, got %q", tc.host, tc.expected, pattern)
+			}
+		})
+	}
+}
+
+// TestHostsValidation tests validation of the host map
+func TestHostsValidation(t *testing.T) {
+	testCases := []struct {
+		name  string
+		hosts map[string]HostConfig
+	}{
+		{"Empty pattern", map[string]HostConfig{"": {}}},
+		{"Wildcard in the middle", map[string]HostConfig{"shop.*.example.com": {}}},
+		{"Pattern with port", map[string]HostConfig{"shop.example.com:443": {}}},
+		{"Duplicate after normalization", map[string]HostConfig{"shop.example.com": {}, "Shop.Example.com": {}}},
+		{"Multiple sources", map[string]HostConfig{"shop.example.com": {MaintenanceContent: "a", MaintenanceService: "http://maintenance"}}},
+		{"Header without value", map[string]HostConfig{"shop.example.com": {BypassHeader: "X-Shop-Bypass"}}},
+		{"Invalid bypass IP", map[string]HostConfig{"shop.example.com": {BypassIPs: []string{"not-an-ip"}}}},
+		{"Invalid bypass path rule", map[string]HostConfig{"shop.example.com": {BypassPathRules: []PathRuleConfig{{Match: "glob", Path: "/["}}}}},
+		{"Invalid bypass value hash", map[string]HostConfig{"shop.example.com": {BypassHeader: "X-Shop-Bypass", BypassHeaderValueHash: "md5:abc"}}},
+		{"Invalid bypass header rule", map[string]HostConfig{"shop.example.com": {BypassHeaderRules: []BypassHeaderRuleConfig{{Name: "ops"}}}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(context.Background(), nil, &Config{
+				MaintenanceContent: "<html><body>Maintenance</body></html>",
+				Hosts:              tc.hosts,
+			}, "hosts-test")
+			if err == nil {
+				t.Errorf("Expected an error but got none")
+			}
+		})
+	}
+}
+
+// TestHosts tests per-host enabled flags, content and bypass settings
+func TestHosts(t *testing.T) {
+	enabled, disabled := true, false
+
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+		rw.Write([]byte("Backend"))
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		ContentType:        "text/html; charset=utf-8",
+		Enabled:            true,
+		BypassHeader:       "X-Maintenance-Bypass",
+		BypassHeaderValue:  "true",
+		Hosts: map[string]HostConfig{
+			"*.tenant.example.com": {
+				MaintenanceContent: "Tenant maintenance",
+				ContentType:        "text/plain",
+				StatusCode:         http.StatusTooManyRequests,
+				BypassHeader:       "X-Tenant-Bypass",
+				BypassHeaderValue:  "tenant-secret",
+				BypassPathRules:    []PathRuleConfig{{Match: "exact", Path: "/status"}},
+			},
+			"live.tenant.example.com": {
+				Enabled: &disabled,
+			},
+			"ops.example.com": {
+				BypassIPs: []string{"192.0.2.0/24"},
+			},
+			"docs.example.com": {
+				BypassPaths: []string{"/public"},
+			},
+		},
+	}, "hosts-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	testCases := []struct {
+		name         string
+		host         string
+		path         string
+		headers      map[string]string
+		remoteAddr   string
+		expectedCode int
+		expectedBody string
+	}{
+		{"Unmatched host uses top-level page", "www.example.com", "/", nil, "", http.StatusServiceUnavailable, "<html><body>Maintenance</body></html>"},
+		{"Unmatched host uses top-level bypass", "www.example.com", "/", map[string]string{"X-Maintenance-Bypass": "true"}, "", http.StatusOK, "Backend"},
+		{"Wildcard host content", "acme.tenant.example.com:8443", "/", nil, "", http.StatusTooManyRequests, "Tenant maintenance"},
+		{"Wildcard host bypass header", "acme.tenant.example.com", "/", map[string]string{"X-Tenant-Bypass": "tenant-secret"}, "", http.StatusOK, "Backend"},
+		{"Wildcard host ignores top-level header", "acme.tenant.example.com", "/", map[string]string{"X-Maintenance-Bypass": "true"}, "", http.StatusTooManyRequests, "Tenant maintenance"},
+		{"Wildcard host bypass path", "acme.tenant.example.com", "/status", nil, "", http.StatusOK, "Backend"},
+		{"Exact host disabled", "live.tenant.example.com", "/", nil, "", http.StatusOK, "Backend"},
+		{"Host bypass IP", "ops.example.com", "/", nil, "192.0.2.10:1234", http.StatusOK, "Backend"},
+		{"Host bypass IP from other range", "ops.example.com", "/", nil, "198.51.100.1:1234", http.StatusServiceUnavailable, "<html><body>Maintenance</body></html>"},
+		{"Host bypass path", "docs.example.com", "/public/guide", nil, "", http.StatusOK, "Backend"},
+		{"Host bypass path replaces top-level paths", "docs.example.com", "/guide", nil, "", http.StatusServiceUnavailable, "<html><body>Maintenance</body></html>"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://"+tc.host+tc.path, nil)
+			for name, value := range tc.headers {
+				req.Header.Set(name, value)
+			}
+			if tc.remoteAddr != "" {
+				req.RemoteAddr = tc.remoteAddr
+			}
+
+			recorder := httptest.NewRecorder()
+			middleware.ServeHTTP(recorder, req)
+
+			if recorder.Code != tc.expectedCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedCode, recorder.Code)
+			}
+
+			if body := recorder.Body.String(); body != tc.expectedBody {
+				t.Errorf("Expected body %q, got %q", tc.expectedBody, body)
+			}
+		})
+	}
+
+	// A host can also turn maintenance on while the top-level flag is off
+	m := middleware.(*MaintenanceBypass)
+	m.enabled = false
+	m.hosts.exact["ops.example.com"].enabled = &enabled
+
+	req := httptest.NewRequest(http.MethodGet, "http://ops.example.com/", nil)
+	req.RemoteAddr = "198.51.100.1:1234"
+	recorder := httptest.NewRecorder()
+	m.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected host enabled flag to turn maintenance on, got status code %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected unmatched host to follow the top-level flag, got status code %d", recorder.Code)
+	}
+}
+
+// TestHostMaintenanceService tests that a host proxies to its own maintenance service with its status code
+func TestHostMaintenanceService(t *testing.T) {
+	service := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte("Shop maintenance for " + req.URL.Path))
+	}))
+	defer service.Close()
+
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		Enabled:            true,
+		Hosts: map[string]HostConfig{
+			"shop.example.com": {
+				MaintenanceService: service.URL,
+				StatusCode:         http.StatusBadGateway,
+			},
+		},
+	}, "hosts-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	middleware.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://shop.example.com/cart", nil))
+
+	if recorder.Code != http.StatusBadGateway {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadGateway, recorder.Code)
+	}
+
+	if body := recorder.Body.String(); body != "Shop maintenance for /cart" {
+		t.Errorf("Expected body from the host's maintenance service, got %q", body)
+	}
+
+	var matcher *hostMatcher
+	if matcher.match("shop.example.com") != nil {
+		t.Errorf("Expected a nil host matcher to match nothing")
+	}
+}
@@ -0,0 +1,159 @@
+package traefik_maintenance_warden
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// maxJWKSSize limits the size of a fetched JSON Web Key Set
+const maxJWKSSize = 1024 * 1024
+
+// jwksFetchTimeout is the timeout of a single JWKS request
+const jwksFetchTimeout = 10 * time.Second
+
+// jwksFetcher caches a JSON Web Key Set fetched from a URL.
+// The last successfully fetched key set stays in use while the endpoint is failing.
+type jwksFetcher struct {
+	url                string
+	client             *http.Client
+	refreshInterval    time.Duration
+	minRefetchInterval time.Duration
+	mutex              sync.Mutex
+	keys               *jwtKeySet
+	etag               string
+	lastFetch          time.Time
+}
+
+// newJWKSFetcher validates the JWKS URL, returning nil if no URL is configured
+func newJWKSFetcher(jwksURL string, refreshInterval int, minRefetchInterval int) (*jwksFetcher, error) {
+	if jwksURL == "" {
+		return nil, nil
+	}
+
+	parsedURL, err := url.Parse(jwksURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWKS URL: %w", err)
+	}
+
+	if parsedURL.Scheme == "" || parsedURL.Host == "" {
+		return nil, fmt.Errorf("JWKS URL must include scheme and host")
+	}
+
+	if refreshInterval <= 0 {
+		refreshInterval = 300
+	}
+
+	if minRefetchInterval <= 0 {
+		minRefetchInterval = 30
+	}
+
+	return &jwksFetcher{
+		url:                jwksURL,
+		client:             &http.Client{Timeout: jwksFetchTimeout},
+		refreshInterval:    time.Duration(refreshInterval) * time.Second,
+		minRefetchInterval: time.Duration(minRefetchInterval) * time.Second,
+	}, nil
+}
+
+// current returns the cached key set, which is nil until the first successful fetch
+func (f *jwksFetcher) current() *jwtKeySet {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.keys
+}
+
+// allowRefetch reports whether an on-demand fetch may happen now, limiting fetches
+// triggered by unknown key IDs to one per minimum refetch interval
+func (f *jwksFetcher) allowRefetch(now time.Time) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if !f.lastFetch.IsZero() && now.Sub(f.lastFetch) < f.minRefetchInterval {
+		return false
+	}
+
+	f.lastFetch = now
+	return true
+}
+
+// fetch downloads the key set once and caches it.
+// The ETag of the last key set is sent so an unchanged key set is not transferred again.
+func (f *jwksFetcher) fetch(ctx context.Context, now time.Time) error {
+	f.mutex.Lock()
+	f.lastFetch = now
+	etag := f.etag
+	f.mutex.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error fetching JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d from JWKS URL", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+	if err != nil {
+		return fmt.Errorf("error reading JWKS: %w", err)
+	}
+
+	keys, err := parseJWKS(body)
+	if err != nil {
+		return err
+	}
+
+	f.mutex.Lock()
+	f.keys = keys
+	f.etag = resp.Header.Get("ETag")
+	f.mutex.Unlock()
+
+	return nil
+}
+
+// refreshJWKS fetches the key set once, logging failures
+func (m *MaintenanceBypass) refreshJWKS(ctx context.Context) {
+	if err := m.jwksFetcher.fetch(ctx, m.currentTime()); err != nil {
+		m.log(LogLevelError, "Failed to fetch JWKS, keeping last known keys: %v", err)
+		return
+	}
+
+	m.log(LogLevelDebug, "Fetched JWKS from %s", m.jwksFetcher.url)
+}
+
+// watchJWKS refreshes the key set until the context is cancelled
+func (m *MaintenanceBypass) watchJWKS(ctx context.Context) {
+	ticker := time.NewTicker(m.jwksFetcher.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		m.refreshJWKS(ctx)
+
+		select {
+		case <-ctx.Done():
+			m.log(LogLevelDebug, "Stopping JWKS refresh")
+			return
+		case <-ticker.C:
+		}
+	}
+}
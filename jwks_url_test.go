@@ -0,0 +1,340 @@
+package traefik_maintenance_warden
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// jwksServer is a stand-in identity provider serving a JSON Web Key Set with an ETag
+type jwksServer struct {
+	mu          sync.Mutex
+	body        []byte
+	etag        string
+	statusCode  int
+	requests    int
+	notModified int
+}
+
+func (s *jwksServer) set(body []byte, etag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.body, s.etag, s.statusCode = body, etag, http.StatusOK
+}
+
+func (s *jwksServer) fail(statusCode int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statusCode = statusCode
+}
+
+func (s *jwksServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *jwksServer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+
+	if s.statusCode != http.StatusOK {
+		rw.WriteHeader(s.statusCode)
+		return
+	}
+
+	if s.etag != "" && req.Header.Get("If-None-Match") == s.etag {
+		s.notModified++
+		rw.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	rw.Header().Set("ETag", s.etag)
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Write(s.body)
+}
+
+// newJWKSTestMiddleware creates a middleware fetching keys from the given URL without starting the refresher.
+// The HMAC secret only satisfies the startup check for a verification key, the test tokens are signed with ES256.
+func newJWKSTestMiddleware(t *testing.T, jwksURL string) *MaintenanceBypass {
+	t.Helper()
+
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent:       "<html><body>Maintenance</body></html>",
+		Enabled:                  true,
+		BypassJWTTokenHeader:     "Authorization",
+		BypassJWTTokenClaim:      "role",
+		BypassJWTTokenClaimValue: "admin",
+		BypassJWTSecret:          "unused",
+	}, "jwks-url-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	m := middleware.(*MaintenanceBypass)
+	m.jwksFetcher, err = newJWKSFetcher(jwksURL, 300, 30)
+	if err != nil {
+		t.Fatalf("Error creating JWKS fetcher: %v", err)
+	}
+
+	return m
+}
+
+// TestJWKSURL tests caching, unknown key ID refetches and failure handling of the JWKS URL
+func TestJWKSURL(t *testing.T) {
+	firstKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating EC key: %v", err)
+	}
+	rotatedKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating EC key: %v", err)
+	}
+
+	server := &jwksServer{}
+	server.set(marshalTestJWKS(t, testJWK("key-1", firstKey)), `"v1"`)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	m := newJWKSTestMiddleware(t, httpServer.URL)
+	now := time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	serve := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		m.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	claims := map[string]interface{}{"role": "admin"}
+	firstToken := signTestJWTWithKey(t, "ES256", "key-1", firstKey, claims)
+	rotatedToken := signTestJWTWithKey(t, "ES256", "key-2", rotatedKey, claims)
+
+	m.refreshJWKS(context.Background())
+	if code := serve(firstToken); code != http.StatusOK {
+		t.Fatalf("Expected token signed by a fetched key to bypass, got status code %d", code)
+	}
+
+	// Known keys are served from the cache
+	serve(firstToken)
+	if requests := server.count(); requests != 1 {
+		t.Errorf("Expected known keys to be served from the cache, got %d requests", requests)
+	}
+
+	// An unchanged key set is not transferred again
+	m.refreshJWKS(context.Background())
+	if server.notModified != 1 {
+		t.Errorf("Expected a conditional request, got %d not modified responses", server.notModified)
+	}
+
+	// Unknown key IDs are rate limited right after a fetch
+	server.set(marshalTestJWKS(t, testJWK("key-1", firstKey), testJWK("key-2", rotatedKey)), `"v2"`)
+	if code := serve(rotatedToken); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected refetch to be rate limited, got status code %d", code)
+	}
+	if requests := server.count(); requests != 2 {
+		t.Errorf("Expected no refetch within the minimum interval, got %d requests", requests)
+	}
+
+	// Once the minimum interval has passed an unknown key ID triggers a refetch
+	now = now.Add(30 * time.Second)
+	if code := serve(rotatedToken); code != http.StatusOK {
+		t.Errorf("Expected token signed by a rotated key to bypass after a refetch, got status code %d", code)
+	}
+	if requests := server.count(); requests != 3 {
+		t.Errorf("Expected a single refetch, got %d requests", requests)
+	}
+
+	// The last good key set stays in use while the endpoint is failing
+	server.fail(http.StatusInternalServerError)
+	m.refreshJWKS(context.Background())
+	if code := serve(firstToken); code != http.StatusOK {
+		t.Errorf("Expected last good key set to be used, got status code %d", code)
+	}
+
+	server.set([]byte(`{"keys":[]}`), `"v3"`)
+	m.refreshJWKS(context.Background())
+	if code := serve(rotatedToken); code != http.StatusOK {
+		t.Errorf("Expected last good key set to be used after an invalid response, got status code %d", code)
+	}
+}
+
+// TestJWKSURLUnavailable tests that tokens are rejected when no key set could be fetched
+func TestJWKSURLUnavailable(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating EC key: %v", err)
+	}
+
+	m := newJWKSTestMiddleware(t, "http://jwks.invalid/keys")
+	m.jwksFetcher.client.Transport = &MockTransportWithError{}
+
+	token := signTestJWTWithKey(t, "ES256", "key-1", key, map[string]interface{}{"role": "admin"})
+	if err := m.verifyJWT(context.Background(), token); err == nil {
+		t.Errorf("Expected token to be rejected without a key set")
+	}
+}
+
+// TestJWKSURLRefetchCancelled tests that a refetch for an unknown key ID stops when the request is cancelled
+func TestJWKSURLRefetchCancelled(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating EC key: %v", err)
+	}
+
+	// The identity provider hangs until the test ends
+	hang := make(chan struct{})
+	httpServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		select {
+		case <-hang:
+		case <-req.Context().Done():
+		}
+	}))
+	defer httpServer.Close()
+	defer close(hang)
+
+	m := newJWKSTestMiddleware(t, httpServer.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil).WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+signTestJWTWithKey(t, "ES256", "key-1", key, map[string]interface{}{"role": "admin"}))
+
+	done := make(chan int)
+	go func() {
+		recorder := httptest.NewRecorder()
+		m.ServeHTTP(recorder, req)
+		done <- recorder.Code
+	}()
+
+	cancel()
+
+	select {
+	case code := <-done:
+		if code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status code %d without a key, got %d", http.StatusServiceUnavailable, code)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected the JWKS refetch to stop when the request is cancelled")
+	}
+}
+
+// TestJWKSURLFetchErrors tests that failed requests and unreadable responses are reported
+func TestJWKSURLFetchErrors(t *testing.T) {
+	fetcher, err := newJWKSFetcher("https://idp.example.com/.well-known/jwks.json", 0, 0)
+	if err != nil {
+		t.Fatalf("Error creating JWKS fetcher: %v", err)
+	}
+
+	fetcher.client.Transport = &MockTransportWithBodyError{}
+	if err := fetcher.fetch(context.Background(), time.Now()); err == nil {
+		t.Errorf("Expected an error for an unreadable response body")
+	}
+
+	fetcher.url = "https://idp.example.com/\x7f"
+	if err := fetcher.fetch(context.Background(), time.Now()); err == nil {
+		t.Errorf("Expected an error for an invalid request URL")
+	}
+}
+
+// TestJWKSURLConfig tests defaults and validation of the JWKS URL configuration
+func TestJWKSURLConfig(t *testing.T) {
+	fetcher, err := newJWKSFetcher("", 0, 0)
+	if err != nil || fetcher != nil {
+		t.Errorf("Expected no fetcher without a URL, got %v (err=%v)", fetcher, err)
+	}
+
+	fetcher, err = newJWKSFetcher("https://idp.example.com/.well-known/jwks.json", 0, 0)
+	if err != nil {
+		t.Fatalf("Error creating JWKS fetcher: %v", err)
+	}
+	if fetcher.refreshInterval != 300*time.Second || fetcher.minRefetchInterval != 30*time.Second {
+		t.Errorf("Unexpected defaults: %+v", fetcher)
+	}
+
+	for _, jwksURL := range []string{"://invalid", "idp.example.com/jwks.json"} {
+		if _, err := New(context.Background(), nil, &Config{
+			MaintenanceContent: "<html><body>Maintenance</body></html>",
+			BypassJWTJWKSURL:   jwksURL,
+		}, "jwks-url-test"); err == nil {
+			t.Errorf("Expected an error for JWKS URL %q", jwksURL)
+		}
+	}
+}
+
+// TestJWKSURLRefreshInterval tests that the key set is fetched again on every refresh interval
+func TestJWKSURLRefreshInterval(t *testing.T) {
+	server := &jwksServer{}
+	server.fail(http.StatusServiceUnavailable)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	fetcher, err := newJWKSFetcher(httpServer.URL, 0, 0)
+	if err != nil {
+		t.Fatalf("Error creating JWKS fetcher: %v", err)
+	}
+	fetcher.refreshInterval = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := &MaintenanceBypass{jwksFetcher: fetcher}
+	go m.watchJWKS(ctx)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for server.count() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if requests := server.count(); requests < 3 {
+		t.Errorf("Expected repeated JWKS fetches, got %d", requests)
+	}
+}
+
+// TestJWKSURLStopsWithContext tests that the JWKS refresher exits when the context is cancelled
+func TestJWKSURLStopsWithContext(t *testing.T) {
+	server := &jwksServer{}
+	server.fail(http.StatusServiceUnavailable)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	middleware, err := New(ctx, nil, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		BypassJWTJWKSURL:   httpServer.URL,
+	}, "jwks-url-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	m := middleware.(*MaintenanceBypass)
+
+	// Run a second refresher directly so its exit can be observed
+	done := make(chan struct{})
+	go func() {
+		m.watchJWKS(ctx)
+		close(done)
+	}()
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected JWKS refresh to stop when the context is cancelled")
+	}
+}
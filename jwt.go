@@ -0,0 +1,320 @@
+package traefik_maintenance_warden
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// jwtHeader is the decoded JOSE header of a JWT token
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// jwtHMACAlgorithms maps the supported HMAC algorithms to their hash functions
+var jwtHMACAlgorithms = map[string]func() hash.Hash{
+	"HS256": sha256.New,
+	"HS384": sha512.New384,
+	"HS512": sha512.New,
+}
+
+// jwtVerificationConfigured reports whether any key is configured to verify JWT token signatures
+func (m *MaintenanceBypass) jwtVerificationConfigured() bool {
+	return len(m.bypassJWTSecret) > 0 || m.jwtPublicKeyFile != nil || m.jwtJWKSFile != nil || m.jwksFetcher != nil
+}
+
+// verifyJWT checks the algorithm, signature and registered claims of a JWT token before any of its
+// claims are trusted. Unsigned tokens and tokens that cannot be verified for lack of a key are always rejected.
+// The context bounds any JWKS refetch needed to find the key of the token.
+func (m *MaintenanceBypass) verifyJWT(ctx context.Context, tokenString string) error {
+	parts := strings.Split(tokenString, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid JWT token format")
+	}
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return fmt.Errorf("error decoding JWT header: %w", err)
+	}
+
+	var header jwtHeader
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return fmt.Errorf("error parsing JWT header: %w", err)
+	}
+
+	if header.Alg == "" || strings.EqualFold(header.Alg, "none") {
+		return fmt.Errorf("unsigned JWT tokens are not accepted")
+	}
+
+	// Without a key to verify against, the claims of the token cannot be trusted
+	if !m.jwtVerificationConfigured() {
+		return fmt.Errorf("no secret or public key configured to verify JWT signatures")
+	}
+
+	if err := m.verifyJWTSignature(ctx, header, parts); err != nil {
+		return err
+	}
+
+	claims, err := parseJWTClaims(tokenString)
+	if err != nil {
+		return err
+	}
+
+	return m.validateJWTClaims(claims)
+}
+
+// verifyJWTSignature verifies the signature of a JWT token with the configured secret or public keys
+func (m *MaintenanceBypass) verifyJWTSignature(ctx context.Context, header jwtHeader, parts []string) error {
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return fmt.Errorf("error decoding JWT signature: %w", err)
+	}
+
+	signingInput := []byte(parts[0] + "." + parts[1])
+
+	if newHash, ok := jwtHMACAlgorithms[header.Alg]; ok {
+		if len(m.bypassJWTSecret) == 0 {
+			return fmt.Errorf("no secret configured for JWT algorithm %s", header.Alg)
+		}
+
+		mac := hmac.New(newHash, m.bypassJWTSecret)
+		mac.Write(signingInput)
+
+		// hmac.Equal compares in constant time
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return fmt.Errorf("invalid JWT signature")
+		}
+		return nil
+	}
+
+	if header.Alg != "RS256" && header.Alg != "ES256" {
+		return fmt.Errorf("unsupported JWT algorithm %s", header.Alg)
+	}
+
+	key := m.jwtPublicKey(ctx, header.Kid, header.Alg)
+	if key == nil {
+		return fmt.Errorf("no public key found for JWT algorithm %s and key ID %q", header.Alg, header.Kid)
+	}
+
+	return verifyJWTPublicKeySignature(key, signingInput, signature)
+}
+
+// validateJWTClaims checks the expiry, not-before, issued-at, issuer and audience claims of a JWT token.
+// Expiry, not-before and issued-at are only checked when present and allow for the configured clock skew.
+func (m *MaintenanceBypass) validateJWTClaims(claims map[string]interface{}) error {
+	now := m.currentTime()
+
+	if exp, ok := claims["exp"]; ok {
+		expiry, ok := exp.(float64)
+		if !ok {
+			return fmt.Errorf("JWT exp claim is not a number")
+		}
+
+		// RFC 7519 rejects tokens on or after their expiry
+		expiresAt := time.Unix(int64(expiry), 0)
+		if !now.Before(expiresAt.Add(m.bypassJWTLeeway)) {
+			return fmt.Errorf("JWT token expired at %s", expiresAt.UTC().Format(time.RFC3339))
+		}
+	}
+
+	if nbf, ok := claims["nbf"]; ok {
+		notBefore, ok := nbf.(float64)
+		if !ok {
+			return fmt.Errorf("JWT nbf claim is not a number")
+		}
+
+		validFrom := time.Unix(int64(notBefore), 0)
+		if now.Add(m.bypassJWTLeeway).Before(validFrom) {
+			return fmt.Errorf("JWT token is not valid before %s", validFrom.UTC().Format(time.RFC3339))
+		}
+	}
+
+	// A token issued in the future points to a clock problem or a forged issue time
+	if iat, ok := claims["iat"]; ok {
+		issued, ok := iat.(float64)
+		if !ok {
+			return fmt.Errorf("JWT iat claim is not a number")
+		}
+
+		issuedAt := time.Unix(int64(issued), 0)
+		if now.Add(m.bypassJWTLeeway).Before(issuedAt) {
+			return fmt.Errorf("JWT token was issued in the future at %s", issuedAt.UTC().Format(time.RFC3339))
+		}
+	}
+
+	if m.bypassJWTIssuer != "" {
+		issuer, _ := claims["iss"].(string)
+		if issuer != m.bypassJWTIssuer {
+			return fmt.Errorf("JWT issuer %q does not match expected issuer %q", issuer, m.bypassJWTIssuer)
+		}
+	}
+
+	if m.bypassJWTAudience != "" && !jwtAudienceContains(claims["aud"], m.bypassJWTAudience) {
+		return fmt.Errorf("JWT audience %v does not include expected audience %q", claims["aud"], m.bypassJWTAudience)
+	}
+
+	return nil
+}
+
+// jwtAudienceContains reports whether an aud claim, either a single string or an array of strings,
+// includes the given audience
+func jwtAudienceContains(aud interface{}, audience string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == audience
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && s == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// verifyJWTPublicKeySignature verifies an RS256 or ES256 signature with a public key
+func verifyJWTPublicKeySignature(key crypto.PublicKey, signingInput []byte, signature []byte) error {
+	digest := sha256.Sum256(signingInput)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature); err != nil {
+			return fmt.Errorf("invalid JWT signature")
+		}
+	case *ecdsa.PublicKey:
+		// ES256 signatures are the concatenated 32-byte R and S values
+		if len(signature) != 64 {
+			return fmt.Errorf("invalid JWT signature length %d", len(signature))
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		sig := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(k, digest[:], r, sig) {
+			return fmt.Errorf("invalid JWT signature")
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+
+	return nil
+}
+
+// parseJWTClaims decodes the claims in the payload of a JWT token without verifying it
+func parseJWTClaims(tokenString string) (map[string]interface{}, error) {
+	// Split the token into parts
+	parts := strings.Split(tokenString, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid JWT token format")
+	}
+
+	// Decode the payload (second part)
+	payloadBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("error decoding JWT payload: %w", err)
+	}
+
+	// Parse the payload
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payloadBytes, &claims); err != nil {
+		return nil, fmt.Errorf("error parsing JWT claims: %w", err)
+	}
+
+	return claims, nil
+}
+
+// lookupJWTClaim finds a claim by name or by a dotted path into nested objects, such as
+// realm_access.roles. Claim names that themselves contain dots take precedence over the path.
+func lookupJWTClaim(claims map[string]interface{}, path string) (interface{}, bool) {
+	if value, ok := claims[path]; ok {
+		return value, true
+	}
+
+	current := interface{}(claims)
+	for _, segment := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		current, ok = object[segment]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// jwtClaimString converts a claim value to a string
+func jwtClaimString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%g", v)
+	case bool:
+		return fmt.Sprintf("%t", v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// getJWTClaimValues extracts a claim from a JWT token as a list of strings.
+// Array claims yield one value per element, any other claim yields a single value.
+func (m *MaintenanceBypass) getJWTClaimValues(tokenString string, claimPath string) ([]string, error) {
+	claims, err := parseJWTClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := lookupJWTClaim(claims, claimPath)
+	if !ok {
+		return nil, fmt.Errorf("claim %s not found in JWT token", claimPath)
+	}
+
+	items, ok := value.([]interface{})
+	if !ok {
+		return []string{jwtClaimString(value)}, nil
+	}
+
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		values = append(values, jwtClaimString(item))
+	}
+
+	return values, nil
+}
+
+// matchJWTClaimValue returns the first claim value that is one of the accepted bypass values
+func (m *MaintenanceBypass) matchJWTClaimValue(claimValues []string) (string, bool) {
+	for _, claimValue := range claimValues {
+		for _, accepted := range m.bypassJWTTokenClaimValues {
+			if claimValue == accepted {
+				return claimValue, true
+			}
+		}
+	}
+	return "", false
+}
+
+// jwtClaimValues combines the single and list forms of the accepted claim values, skipping empty values
+func jwtClaimValues(value string, values []string) []string {
+	var accepted []string
+	for _, v := range append([]string{value}, values...) {
+		if v != "" {
+			accepted = append(accepted, v)
+		}
+	}
+	return accepted
+}
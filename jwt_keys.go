@@ -0,0 +1,277 @@
+package traefik_maintenance_warden
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// jwtKey is a public key used to verify JWT token signatures
+type jwtKey struct {
+	kid string
+	alg string
+	key crypto.PublicKey
+}
+
+// jwtKeySet is a set of public keys, selected by key ID and algorithm
+type jwtKeySet struct {
+	keys []jwtKey
+}
+
+// find returns the key matching the key ID and algorithm of a token, or nil if there is none.
+// Keys without a key ID, such as those loaded from a PEM file, match any token.
+func (s *jwtKeySet) find(kid string, alg string) crypto.PublicKey {
+	if s == nil {
+		return nil
+	}
+
+	for _, key := range s.keys {
+		if key.kid != "" && kid != "" && key.kid != kid {
+			continue
+		}
+		if key.alg != "" && key.alg != alg {
+			continue
+		}
+		if !jwtKeySupportsAlgorithm(key.key, alg) {
+			continue
+		}
+		return key.key
+	}
+
+	return nil
+}
+
+// jwtKeySupportsAlgorithm reports whether a public key can verify signatures of the given algorithm
+func jwtKeySupportsAlgorithm(key crypto.PublicKey, alg string) bool {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return alg == "RS256"
+	case *ecdsa.PublicKey:
+		return alg == "ES256" && k.Curve == elliptic.P256()
+	default:
+		return false
+	}
+}
+
+// parsePEMKeys parses the public keys and certificates in a PEM file
+func parsePEMKeys(data []byte) (*jwtKeySet, error) {
+	set := &jwtKeySet{}
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		var key crypto.PublicKey
+		var err error
+		switch block.Type {
+		case "PUBLIC KEY":
+			key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var cert *x509.Certificate
+			cert, err = x509.ParseCertificate(block.Bytes)
+			if err == nil {
+				key = cert.PublicKey
+			}
+		default:
+			return nil, fmt.Errorf("unsupported PEM block type %s", block.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", block.Type, err)
+		}
+
+		if !jwtKeySupportsAlgorithm(key, "RS256") && !jwtKeySupportsAlgorithm(key, "ES256") {
+			return nil, fmt.Errorf("unsupported public key type %T, only RSA and P-256 keys are supported", key)
+		}
+
+		set.keys = append(set.keys, jwtKey{key: key})
+	}
+
+	if len(set.keys) == 0 {
+		return nil, fmt.Errorf("no public keys found in PEM data")
+	}
+
+	return set, nil
+}
+
+// jwk is a single JSON Web Key as defined in RFC 7517
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// parseJWKS parses a JSON Web Key Set. Encryption keys and unsupported key types are skipped.
+func parseJWKS(data []byte) (*jwtKeySet, error) {
+	var document struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("error parsing JWKS: %w", err)
+	}
+
+	set := &jwtKeySet{}
+	for i, key := range document.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+
+		var publicKey crypto.PublicKey
+		var err error
+		switch key.Kty {
+		case "RSA":
+			publicKey, err = parseRSAJWK(key)
+		case "EC":
+			publicKey, err = parseECJWK(key)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %d in JWKS: %w", i, err)
+		}
+
+		set.keys = append(set.keys, jwtKey{kid: key.Kid, alg: key.Alg, key: publicKey})
+	}
+
+	if len(set.keys) == 0 {
+		return nil, fmt.Errorf("no supported signing keys found in JWKS")
+	}
+
+	return set, nil
+}
+
+// parseRSAJWK builds an RSA public key from its modulus and exponent
+func parseRSAJWK(key jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(key.N)
+	if err != nil || len(n) == 0 {
+		return nil, fmt.Errorf("invalid RSA modulus")
+	}
+
+	e, err := base64.RawURLEncoding.DecodeString(key.E)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, fmt.Errorf("invalid RSA exponent")
+	}
+
+	exponent := int(new(big.Int).SetBytes(e).Int64())
+	if exponent < 3 {
+		return nil, fmt.Errorf("invalid RSA exponent")
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}, nil
+}
+
+// parseECJWK builds a P-256 public key from its coordinates
+func parseECJWK(key jwk) (*ecdsa.PublicKey, error) {
+	if key.Crv != "P-256" {
+		return nil, fmt.Errorf("unsupported curve %q, only P-256 is supported", key.Crv)
+	}
+
+	x, errX := base64.RawURLEncoding.DecodeString(key.X)
+	y, errY := base64.RawURLEncoding.DecodeString(key.Y)
+	if errX != nil || errY != nil || len(x) != 32 || len(y) != 32 {
+		return nil, fmt.Errorf("invalid EC coordinates")
+	}
+
+	curve := elliptic.P256()
+	publicKey := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	if !curve.IsOnCurve(publicKey.X, publicKey.Y) {
+		return nil, fmt.Errorf("EC point is not on curve P-256")
+	}
+
+	return publicKey, nil
+}
+
+// jwtKeyFile holds keys loaded from a file, reloading them when the file changes
+type jwtKeyFile struct {
+	path    string
+	parse   func([]byte) (*jwtKeySet, error)
+	mutex   sync.Mutex
+	lastMod time.Time
+	keys    *jwtKeySet
+}
+
+// load returns the keys in the file, reading it again if it was modified since the last load.
+// If reloading fails the previously loaded keys are returned along with the error.
+func (f *jwtKeyFile) load() (*jwtKeySet, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	content, modTime, modified, err := readFileIfModified(f.path, f.lastMod)
+	if err != nil {
+		return f.keys, fmt.Errorf("error loading key file: %w", err)
+	}
+
+	if !modified {
+		return f.keys, nil
+	}
+
+	// An invalid file is only read and parsed again once it changes
+	f.lastMod = modTime
+
+	keys, err := f.parse(content)
+	if err != nil {
+		return f.keys, fmt.Errorf("error parsing key file %s: %w", f.path, err)
+	}
+
+	f.keys = keys
+
+	return keys, nil
+}
+
+// jwtPublicKey returns the configured public key matching the key ID and algorithm of a token.
+// A refetch of the JWKS URL for an unknown key ID is cancelled along with the context.
+func (m *MaintenanceBypass) jwtPublicKey(ctx context.Context, kid string, alg string) crypto.PublicKey {
+	for _, file := range []*jwtKeyFile{m.jwtPublicKeyFile, m.jwtJWKSFile} {
+		if file == nil {
+			continue
+		}
+
+		keys, err := file.load()
+		if err != nil {
+			m.log(LogLevelError, "Error loading JWT keys: %v", err)
+		}
+
+		if key := keys.find(kid, alg); key != nil {
+			return key
+		}
+	}
+
+	if m.jwksFetcher == nil {
+		return nil
+	}
+
+	if key := m.jwksFetcher.current().find(kid, alg); key != nil {
+		return key
+	}
+
+	// An unknown key ID may mean the keys were rotated, so fetch them again unless that happened recently
+	if !m.jwksFetcher.allowRefetch(m.currentTime()) {
+		m.log(LogLevelDebug, "No JWKS key found for key ID %q, refetch is rate limited", kid)
+		return nil
+	}
+
+	m.log(LogLevelDebug, "No JWKS key found for key ID %q, refetching JWKS", kid)
+	m.refreshJWKS(ctx)
+
+	return m.jwksFetcher.current().find(kid, alg)
+}
@@ -0,0 +1,376 @@
+package traefik_maintenance_warden
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// signTestJWT builds an HMAC-signed JWT token for tests
+func signTestJWT(t *testing.T, alg string, newHash func() hash.Hash, secret string, claims map[string]interface{}) string {
+	t.Helper()
+
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	if err != nil {
+		t.Fatalf("Error encoding header: %v", err)
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("Error encoding claims: %v", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	if newHash == nil {
+		return signingInput + "."
+	}
+
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// TestVerifyJWTHMAC tests HMAC signature verification of JWT tokens
+func TestVerifyJWTHMAC(t *testing.T) {
+	claims := map[string]interface{}{"role": "admin"}
+
+	// A correctly signed token whose payload is not a JSON object
+	notJSON := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256"}`)) + "." + base64.RawURLEncoding.EncodeToString([]byte("not-json"))
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(notJSON))
+	notJSON += "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	testCases := []struct {
+		name        string
+		secret      string
+		token       string
+		expectError bool
+	}{
+		{"Valid HS256", "s3cret", signTestJWT(t, "HS256", jwtHMACAlgorithms["HS256"], "s3cret", claims), false},
+		{"Valid HS384", "s3cret", signTestJWT(t, "HS384", jwtHMACAlgorithms["HS384"], "s3cret", claims), false},
+		{"Valid HS512", "s3cret", signTestJWT(t, "HS512", jwtHMACAlgorithms["HS512"], "s3cret", claims), false},
+		{"Wrong secret", "s3cret", signTestJWT(t, "HS256", sha256.New, "other", claims), true},
+		{"Algorithm mismatch", "s3cret", signTestJWT(t, "HS512", sha256.New, "s3cret", claims), true},
+		{"Unsupported algorithm", "s3cret", signTestJWT(t, "RS256", sha256.New, "s3cret", claims), true},
+		{"Unsigned token", "s3cret", signTestJWT(t, "none", nil, "", claims), true},
+		{"Unsigned token without secret", "", signTestJWT(t, "none", nil, "", claims), true},
+		{"Uppercase none without secret", "", signTestJWT(t, "NONE", nil, "", claims), true},
+		{"Signed token without secret", "", signTestJWT(t, "HS256", sha256.New, "anything", claims), true},
+		{"Invalid signature encoding", "s3cret", signTestJWT(t, "HS256", nil, "", claims) + "!!", true},
+		{"Invalid header encoding", "s3cret", "!!.e30.sig", true},
+		{"Invalid header JSON", "s3cret", "bm90LWpzb24.e30.sig", true},
+		{"Wrong number of parts", "s3cret", "header.payload", true},
+		{"Signed payload that is not JSON", "s3cret", notJSON, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &MaintenanceBypass{bypassJWTSecret: []byte(tc.secret)}
+
+			err := m.verifyJWT(context.Background(), tc.token)
+			if tc.expectError && err == nil {
+				t.Errorf("Expected an error but got none")
+			}
+			if !tc.expectError && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+// TestJWTSecretBypass tests that only correctly signed tokens bypass maintenance mode
+func TestJWTSecretBypass(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent:       "<html><body>Maintenance</body></html>",
+		Enabled:                  true,
+		BypassJWTTokenHeader:     "Authorization",
+		BypassJWTTokenClaim:      "role",
+		BypassJWTTokenClaimValue: "admin",
+		BypassJWTSecret:          "s3cret",
+	}, "jwt-secret-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	claims := map[string]interface{}{"role": "admin"}
+
+	testCases := []struct {
+		name           string
+		token          string
+		expectedStatus int
+	}{
+		{"Correctly signed token", signTestJWT(t, "HS256", sha256.New, "s3cret", claims), http.StatusOK},
+		{"Forged signature", signTestJWT(t, "HS256", sha256.New, "guess", claims), http.StatusServiceUnavailable},
+		{"Unsigned token", signTestJWT(t, "none", nil, "", claims), http.StatusServiceUnavailable},
+		{"Garbage signature", signTestJWT(t, "HS256", nil, "", claims) + "forged", http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+
+			recorder := httptest.NewRecorder()
+			middleware.ServeHTTP(recorder, req)
+
+			if recorder.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, recorder.Code)
+			}
+		})
+	}
+}
+
+// TestJWTBypassRequiresVerificationKey tests that JWT bypass cannot be configured without a key to verify signatures
+func TestJWTBypassRequiresVerificationKey(t *testing.T) {
+	_, err := New(context.Background(), nil, &Config{
+		MaintenanceContent:       "<html><body>Maintenance</body></html>",
+		BypassJWTTokenClaim:      "role",
+		BypassJWTTokenClaimValue: "admin",
+	}, "jwt-secret-test")
+	if err == nil {
+		t.Errorf("Expected an error for a JWT bypass without a secret or public key")
+	}
+}
+
+// TestValidateJWTClaims tests expiry, not-before, issuer and audience validation
+func TestValidateJWTClaims(t *testing.T) {
+	now := time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC)
+	unix := float64(now.Unix())
+
+	testCases := []struct {
+		name        string
+		leeway      time.Duration
+		issuer      string
+		audience    string
+		claims      map[string]interface{}
+		expectError bool
+	}{
+		{"No registered claims", 0, "", "", map[string]interface{}{"role": "admin"}, false},
+		{"Not yet expired", 0, "", "", map[string]interface{}{"exp": unix + 60}, false},
+		{"Expired", 0, "", "", map[string]interface{}{"exp": unix - 1}, true},
+		{"Expires now", 0, "", "", map[string]interface{}{"exp": unix}, true},
+		{"Expires at the end of the leeway", 30 * time.Second, "", "", map[string]interface{}{"exp": unix - 30}, true},
+		{"Expired within leeway", 30 * time.Second, "", "", map[string]interface{}{"exp": unix - 20}, false},
+		{"Expired beyond leeway", 30 * time.Second, "", "", map[string]interface{}{"exp": unix - 40}, true},
+		{"Invalid exp", 0, "", "", map[string]interface{}{"exp": "tomorrow"}, true},
+		{"Already valid", 0, "", "", map[string]interface{}{"nbf": unix}, false},
+		{"Not yet valid", 0, "", "", map[string]interface{}{"nbf": unix + 10}, true},
+		{"Not yet valid within leeway", 30 * time.Second, "", "", map[string]interface{}{"nbf": unix + 20}, false},
+		{"Invalid nbf", 0, "", "", map[string]interface{}{"nbf": true}, true},
+		{"Issued in the past", 0, "", "", map[string]interface{}{"iat": unix - 60}, false},
+		{"Issued now", 0, "", "", map[string]interface{}{"iat": unix}, false},
+		{"Issued in the future", 0, "", "", map[string]interface{}{"iat": unix + 10}, true},
+		{"Issued in the future within leeway", 30 * time.Second, "", "", map[string]interface{}{"iat": unix + 20}, false},
+		{"Invalid iat", 0, "", "", map[string]interface{}{"iat": "now"}, true},
+		{"Matching issuer", 0, "https://idp.example.com", "", map[string]interface{}{"iss": "https://idp.example.com"}, false},
+		{"Wrong issuer", 0, "https://idp.example.com", "", map[string]interface{}{"iss": "https://evil.example.com"}, true},
+		{"Missing issuer", 0, "https://idp.example.com", "", map[string]interface{}{}, true},
+		{"Matching audience", 0, "", "warden", map[string]interface{}{"aud": "warden"}, false},
+		{"Audience in list", 0, "", "warden", map[string]interface{}{"aud": []interface{}{"api", "warden"}}, false},
+		{"Audience not in list", 0, "", "warden", map[string]interface{}{"aud": []interface{}{"api", 42}}, true},
+		{"Missing audience", 0, "", "warden", map[string]interface{}{}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &MaintenanceBypass{
+				bypassJWTLeeway:   tc.leeway,
+				bypassJWTIssuer:   tc.issuer,
+				bypassJWTAudience: tc.audience,
+				now:               func() time.Time { return now },
+			}
+
+			err := m.validateJWTClaims(tc.claims)
+			if tc.expectError && err == nil {
+				t.Errorf("Expected an error but got none")
+			}
+			if !tc.expectError && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+// TestJWTExpiredTokenBypass tests that expired tokens no longer bypass maintenance mode
+func TestJWTExpiredTokenBypass(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent:       "<html><body>Maintenance</body></html>",
+		Enabled:                  true,
+		BypassJWTTokenHeader:     "Authorization",
+		BypassJWTTokenClaim:      "role",
+		BypassJWTTokenClaimValue: "admin",
+		BypassJWTSecret:          "s3cret",
+		BypassJWTLeeway:          60,
+		BypassJWTIssuer:          "https://idp.example.com",
+	}, "jwt-claims-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	m := middleware.(*MaintenanceBypass)
+	now := time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	token := signTestJWT(t, "HS256", sha256.New, "s3cret", map[string]interface{}{
+		"role": "admin",
+		"iss":  "https://idp.example.com",
+		"exp":  now.Add(time.Minute).Unix(),
+	})
+
+	serve := func() int {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		m.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	if code := serve(); code != http.StatusOK {
+		t.Errorf("Expected valid token to bypass, got status code %d", code)
+	}
+
+	now = now.Add(90 * time.Second)
+	if code := serve(); code != http.StatusOK {
+		t.Errorf("Expected token within the leeway to bypass, got status code %d", code)
+	}
+
+	now = now.Add(time.Minute)
+	if code := serve(); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected expired token to be rejected, got status code %d", code)
+	}
+
+	if _, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		BypassJWTLeeway:    -1,
+	}, "jwt-claims-test"); err == nil {
+		t.Errorf("Expected an error for a negative leeway")
+	}
+}
+
+// TestGetJWTClaimValues tests dotted claim paths and array claims
+func TestGetJWTClaimValues(t *testing.T) {
+	token := signTestJWT(t, "HS256", sha256.New, "s3cret", map[string]interface{}{
+		"role":                       "admin",
+		"groups":                     []interface{}{"ops", "dev", 7},
+		"https://example.com/tenant": "acme",
+		"realm_access": map[string]interface{}{
+			"roles": []interface{}{"offline_access", "maintainer"},
+		},
+	})
+
+	testCases := []struct {
+		name        string
+		path        string
+		expected    []string
+		expectError bool
+	}{
+		{"Top-level string", "role", []string{"admin"}, false},
+		{"Top-level array", "groups", []string{"ops", "dev", "7"}, false},
+		{"Nested array", "realm_access.roles", []string{"offline_access", "maintainer"}, false},
+		{"Claim name containing dots", "https://example.com/tenant", []string{"acme"}, false},
+		{"Missing nested claim", "realm_access.groups", nil, true},
+		{"Path through a scalar", "role.name", nil, true},
+	}
+
+	m := &MaintenanceBypass{}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			values, err := m.getJWTClaimValues(token, tc.path)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected an error but got values %v", values)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if len(values) != len(tc.expected) {
+				t.Fatalf("Expected values %v, got %v", tc.expected, values)
+			}
+			for i := range values {
+				if values[i] != tc.expected[i] {
+					t.Errorf("Expected values %v, got %v", tc.expected, values)
+				}
+			}
+		})
+	}
+
+	if _, err := m.getJWTClaimValues("invalid", "role"); err == nil {
+		t.Errorf("Expected an error for an invalid token")
+	}
+}
+
+// TestJWTClaimValuesBypass tests bypass with array claims and a list of accepted values
+func TestJWTClaimValuesBypass(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent:        "<html><body>Maintenance</body></html>",
+		Enabled:                   true,
+		BypassJWTTokenHeader:      "Authorization",
+		BypassJWTTokenClaim:       "realm_access.roles",
+		BypassJWTTokenClaimValues: []string{"maintainer", "admin"},
+		BypassJWTSecret:           "s3cret",
+	}, "jwt-claim-values-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	testCases := []struct {
+		name           string
+		roles          interface{}
+		expectedStatus int
+	}{
+		{"Array containing an accepted value", []interface{}{"user", "admin"}, http.StatusOK},
+		{"Array without an accepted value", []interface{}{"user", "viewer"}, http.StatusServiceUnavailable},
+		{"Single accepted value", "maintainer", http.StatusOK},
+		{"Empty array", []interface{}{}, http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			token := signTestJWT(t, "HS256", sha256.New, "s3cret", map[string]interface{}{
+				"realm_access": map[string]interface{}{"roles": tc.roles},
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			recorder := httptest.NewRecorder()
+			middleware.ServeHTTP(recorder, req)
+
+			if recorder.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, recorder.Code)
+			}
+		})
+	}
+
+	// A correctly signed token without the claim does not bypass
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	req.Header.Set("Authorization", "Bearer "+signTestJWT(t, "HS256", sha256.New, "s3cret", map[string]interface{}{"role": "admin"}))
+	recorder := httptest.NewRecorder()
+	middleware.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected a token without the claim not to bypass, got status code %d", recorder.Code)
+	}
+}
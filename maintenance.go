@@ -4,11 +4,9 @@ package traefik_maintenance_warden
 
 import (
 	"context"
-	"encoding/base64"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
@@ -32,6 +30,13 @@ const (
 	LogLevelDebug
 )
 
+const (
+	// retryAfterFormatSeconds sends Retry-After as a number of seconds
+	retryAfterFormatSeconds = "seconds"
+	// retryAfterFormatHTTPDate sends Retry-After as an HTTP-date
+	retryAfterFormatHTTPDate = "http-date"
+)
+
 // Config holds the plugin configuration.
 type Config struct {
 	// MaintenanceService is the URL of the maintenance service to redirect to
@@ -43,12 +48,22 @@ type Config struct {
 	// MaintenanceContent is the direct HTML content to serve instead of a file or service
 	MaintenanceContent string `json:"maintenanceContent,omitempty"`
 
+	// MaintenanceJSONContent is the body served to clients preferring JSON, instead of a built-in JSON body
+	MaintenanceJSONContent string `json:"maintenanceJSONContent,omitempty"`
+
+	// MaintenanceTextContent is the body served to clients preferring plain text
+	MaintenanceTextContent string `json:"maintenanceTextContent,omitempty"`
+
 	// BypassHeader is the header name that allows bypassing maintenance mode
 	BypassHeader string `json:"bypassHeader,omitempty"`
 
 	// BypassHeaderValue is the expected value of the bypass header
 	BypassHeaderValue string `json:"bypassHeaderValue,omitempty"`
 
+	// BypassHeaderValueHash is a hash of the expected bypass header value, used instead of BypassHeaderValue.
+	// Supported forms are sha256:<hex digest> and pbkdf2-sha256:<iterations>:<hex salt>:<hex key>.
+	BypassHeaderValueHash string `json:"bypassHeaderValueHash,omitempty"`
+
 	// BypassJWTTokenHeader is the header containing the JWT token
 	BypassJWTTokenHeader string `json:"bypassJWTTokenHeader,omitempty"`
 
@@ -58,6 +73,36 @@ type Config struct {
 	// BypassJWTTokenClaimValue is the expected value of the JWT token claim
 	BypassJWTTokenClaimValue string `json:"bypassJWTTokenClaimValue,omitempty"`
 
+	// BypassJWTTokenClaimValues is a list of accepted values of the JWT token claim, in addition to BypassJWTTokenClaimValue
+	BypassJWTTokenClaimValues []string `json:"bypassJWTTokenClaimValues,omitempty"`
+
+	// BypassJWTSecret is the shared secret used to verify HS256/HS384/HS512 JWT token signatures
+	BypassJWTSecret string `json:"bypassJWTSecret,omitempty"`
+
+	// BypassJWTPublicKeyFile is the path to a PEM file with the public key used to verify RS256/ES256 JWT token signatures
+	BypassJWTPublicKeyFile string `json:"bypassJWTPublicKeyFile,omitempty"`
+
+	// BypassJWTJWKSFile is the path to a JSON Web Key Set file used to verify RS256/ES256 JWT token signatures
+	BypassJWTJWKSFile string `json:"bypassJWTJWKSFile,omitempty"`
+
+	// BypassJWTJWKSURL is the URL of a JSON Web Key Set used to verify RS256/ES256 JWT token signatures
+	BypassJWTJWKSURL string `json:"bypassJWTJWKSURL,omitempty"`
+
+	// BypassJWTJWKSRefreshInterval is how often the JWKS URL is fetched, in seconds
+	BypassJWTJWKSRefreshInterval int `json:"bypassJWTJWKSRefreshInterval,omitempty"`
+
+	// BypassJWTJWKSMinRefetchInterval is the minimum time between fetches triggered by unknown key IDs, in seconds
+	BypassJWTJWKSMinRefetchInterval int `json:"bypassJWTJWKSMinRefetchInterval,omitempty"`
+
+	// BypassJWTLeeway is the clock skew allowed when checking the exp, nbf and iat claims of JWT tokens, in seconds
+	BypassJWTLeeway int `json:"bypassJWTLeeway,omitempty"`
+
+	// BypassJWTIssuer is the required iss claim of JWT tokens, if set
+	BypassJWTIssuer string `json:"bypassJWTIssuer,omitempty"`
+
+	// BypassJWTAudience is the audience that the aud claim of JWT tokens must include, if set
+	BypassJWTAudience string `json:"bypassJWTAudience,omitempty"`
+
 	// Enabled controls whether the maintenance mode is active
 	Enabled bool `json:"enabled,omitempty"`
 
@@ -67,6 +112,16 @@ type Config struct {
 	// BypassPaths are paths that should bypass maintenance mode
 	BypassPaths []string `json:"bypassPaths,omitempty"`
 
+	// BypassPathRules are path rules with an explicit match type that bypass maintenance mode
+	BypassPathRules []PathRuleConfig `json:"bypassPathRules,omitempty"`
+
+	// MaintenancePaths limits maintenance mode to requests matching one of the path groups
+	MaintenancePaths []MaintenancePathConfig `json:"maintenancePaths,omitempty"`
+
+	// Hosts configures maintenance mode per host pattern, such as *.tenant.example.com.
+	// Requests to hosts without a matching pattern use the top-level configuration.
+	Hosts map[string]HostConfig `json:"hosts,omitempty"`
+
 	// BypassFavicon controls whether favicon.ico requests bypass maintenance mode
 	BypassFavicon bool `json:"bypassFavicon,omitempty"`
 
@@ -78,53 +133,198 @@ type Config struct {
 
 	// ContentType is the content type header to set when serving the maintenance file
 	ContentType string `json:"contentType,omitempty"`
+
+	// Schedule restricts maintenance mode to the configured time windows
+	Schedule ScheduleConfig `json:"schedule,omitempty"`
+
+	// MaintenanceEndTime is the RFC3339 time at which maintenance is expected to end
+	MaintenanceEndTime string `json:"maintenanceEndTime,omitempty"`
+
+	// RetryAfterFormat controls the Retry-After header format ("seconds" or "http-date")
+	RetryAfterFormat string `json:"retryAfterFormat,omitempty"`
+
+	// DefaultRetryAfter is the Retry-After value in seconds used when no maintenance end is known
+	DefaultRetryAfter int `json:"defaultRetryAfter,omitempty"`
+
+	// EnabledFlagFile is the path to a file whose presence turns maintenance mode on
+	EnabledFlagFile string `json:"enabledFlagFile,omitempty"`
+
+	// FlagFilePollInterval is how often the flag file is checked, in seconds
+	FlagFilePollInterval int `json:"flagFilePollInterval,omitempty"`
+
+	// AdminSecret enables the admin API and is the bearer token required to use it
+	AdminSecret string `json:"adminSecret,omitempty"`
+
+	// AdminPathPrefix is the reserved path prefix under which the admin API is served
+	AdminPathPrefix string `json:"adminPathPrefix,omitempty"`
+
+	// BypassHeaderRules is a list of named bypass header rules, used instead of BypassHeader and BypassHeaderValue
+	BypassHeaderRules []BypassHeaderRuleConfig `json:"bypassHeaderRules,omitempty"`
+
+	// BypassIPs is a list of client IP addresses or CIDR ranges that bypass maintenance mode
+	BypassIPs []string `json:"bypassIPs,omitempty"`
+
+	// TrustedProxies is a list of proxy IP addresses or CIDR ranges whose X-Forwarded-For and X-Real-IP headers are trusted
+	TrustedProxies []string `json:"trustedProxies,omitempty"`
+
+	// BypassTokenSecret is the shared secret used to sign bypass link tokens and cookies
+	BypassTokenSecret string `json:"bypassTokenSecret,omitempty"`
+
+	// BypassCookieName is the name of the cookie set by the bypass link
+	BypassCookieName string `json:"bypassCookieName,omitempty"`
+
+	// BypassTokenHeader is the header carrying a signed bypass token
+	BypassTokenHeader string `json:"bypassTokenHeader,omitempty"`
+
+	// StateURL is the URL of a JSON document controlling whether maintenance mode is enabled
+	StateURL string `json:"stateURL,omitempty"`
+
+	// StatePollInterval is how often the state URL is polled, in seconds
+	StatePollInterval int `json:"statePollInterval,omitempty"`
+
+	// Mode selects what maintenance blocks: "full" blocks everything, "readonly" only blocks writes
+	Mode string `json:"mode,omitempty"`
+
+	// ReadOnlyAllowedMethods are the HTTP methods passed through in read-only mode
+	ReadOnlyAllowedMethods []string `json:"readOnlyAllowedMethods,omitempty"`
+
+	// PassThroughPercent is the share of users (0-100) let through to the service during maintenance
+	PassThroughPercent int `json:"passThroughPercent,omitempty"`
+
+	// PassThroughKeySource selects what identifies a user for the pass-through share ("ip", "cookie" or "header")
+	PassThroughKeySource string `json:"passThroughKeySource,omitempty"`
+
+	// PassThroughKeyName is the cookie or header name used when PassThroughKeySource is "cookie" or "header"
+	PassThroughKeyName string `json:"passThroughKeyName,omitempty"`
+
+	// AutoTrigger turns maintenance mode on automatically while the upstream is failing
+	AutoTrigger AutoTriggerConfig `json:"autoTrigger,omitempty"`
+
+	// HealthCheck turns maintenance mode on automatically while the backend is unhealthy
+	HealthCheck HealthCheckConfig `json:"healthCheck,omitempty"`
+
+	// ProblemDetails generates RFC 9457 application/problem+json maintenance responses
+	ProblemDetails ProblemDetailsConfig `json:"problemDetails,omitempty"`
 }
 
 // CreateConfig creates the default plugin configuration.
 func CreateConfig() *Config {
 	return &Config{
-		MaintenanceService:      "",
-		MaintenanceFilePath:     "",
-		MaintenanceContent:      "",
-		BypassHeader:            "X-Maintenance-Bypass",
-		BypassHeaderValue:       "true",
-		BypassJWTTokenHeader:    "Authorization",
-		BypassJWTTokenClaim:     "",
-		BypassJWTTokenClaimValue: "",
-		Enabled:                 true,
-		StatusCode:              503,
-		BypassPaths:             []string{},
-		BypassFavicon:           true,
-		LogLevel:                int(LogLevelError),
-		MaintenanceTimeout:      10,
-		ContentType:             "text/html; charset=utf-8",
+		MaintenanceService:              "",
+		MaintenanceFilePath:             "",
+		MaintenanceContent:              "",
+		MaintenanceJSONContent:          "",
+		MaintenanceTextContent:          "",
+		BypassHeader:                    "X-Maintenance-Bypass",
+		BypassHeaderValue:               "true",
+		BypassHeaderValueHash:           "",
+		BypassJWTTokenHeader:            "Authorization",
+		BypassJWTTokenClaim:             "",
+		BypassJWTTokenClaimValue:        "",
+		BypassJWTTokenClaimValues:       []string{},
+		BypassJWTSecret:                 "",
+		BypassJWTPublicKeyFile:          "",
+		BypassJWTJWKSFile:               "",
+		BypassJWTJWKSURL:                "",
+		BypassJWTJWKSRefreshInterval:    300,
+		BypassJWTJWKSMinRefetchInterval: 30,
+		BypassJWTLeeway:                 0,
+		BypassJWTIssuer:                 "",
+		BypassJWTAudience:               "",
+		Enabled:                         true,
+		StatusCode:                      503,
+		BypassPaths:                     []string{},
+		BypassPathRules:                 []PathRuleConfig{},
+		MaintenancePaths:                []MaintenancePathConfig{},
+		Hosts:                           map[string]HostConfig{},
+		BypassFavicon:                   true,
+		LogLevel:                        int(LogLevelError),
+		MaintenanceTimeout:              10,
+		ContentType:                     "text/html; charset=utf-8",
+		RetryAfterFormat:                retryAfterFormatSeconds,
+		DefaultRetryAfter:               3600,
+		EnabledFlagFile:                 "",
+		FlagFilePollInterval:            5,
+		AdminSecret:                     "",
+		AdminPathPrefix:                 "/.warden/",
+		BypassHeaderRules:               []BypassHeaderRuleConfig{},
+		BypassIPs:                       []string{},
+		TrustedProxies:                  []string{},
+		BypassTokenSecret:               "",
+		BypassCookieName:                "warden_bypass",
+		BypassTokenHeader:               "X-Maintenance-Bypass-Token",
+		StateURL:                        "",
+		StatePollInterval:               10,
+		Mode:                            modeFull,
+		ReadOnlyAllowedMethods:          []string{http.MethodGet, http.MethodHead, http.MethodOptions},
+		PassThroughPercent:              0,
+		PassThroughKeySource:            passThroughKeySourceIP,
+		PassThroughKeyName:              "",
 	}
 }
 
 // MaintenanceBypass is a middleware that redirects all traffic to a maintenance page
 // unless the request has a specific bypass header.
 type MaintenanceBypass struct {
-	next                   http.Handler
-	maintenanceService     *url.URL
-	maintenanceFilePath    string
-	maintenanceFileContent []byte
-	maintenanceContent     string
-	maintenanceFileLastMod time.Time
-	fileMutex              sync.RWMutex
-	bypassHeader           string
-	bypassHeaderValue      string
-	bypassJWTTokenHeader   string
-	bypassJWTTokenClaim    string
-	bypassJWTTokenClaimValue string
-	enabled                bool
-	statusCode             int
-	bypassPaths            []string
-	bypassFavicon          bool
-	name                   string
-	logger                 *log.Logger
-	logLevel               LogLevel
-	timeout                time.Duration
-	contentType            string
+	next                      http.Handler
+	maintenanceService        *url.URL
+	maintenanceFile           *maintenanceFile
+	maintenanceContent        string
+	maintenanceJSONContent    string
+	maintenanceTextContent    string
+	bypassHeaderRules         []bypassHeaderRule
+	bypassJWTTokenHeader      string
+	bypassJWTTokenClaim       string
+	bypassJWTTokenClaimValues []string
+	bypassJWTSecret           []byte
+	jwtPublicKeyFile          *jwtKeyFile
+	jwtJWKSFile               *jwtKeyFile
+	jwksFetcher               *jwksFetcher
+	bypassJWTLeeway           time.Duration
+	bypassJWTIssuer           string
+	bypassJWTAudience         string
+	enabled                   bool
+	forceEnabled              bool
+	stateMutex                sync.RWMutex
+	statusCode                int
+	bypassPaths               []string
+	bypassPathRules           []pathRule
+	maintenancePaths          []maintenancePathGroup
+	hosts                     *hostMatcher
+	bypassFavicon             bool
+	name                      string
+	logger                    *log.Logger
+	logLevel                  LogLevel
+	timeout                   time.Duration
+	contentType               string
+	schedule                  *schedule
+	maintenanceEndTime        time.Time
+	retryAfterFormat          string
+	defaultRetryAfter         time.Duration
+	enabledFlagFile           string
+	flagFilePollInterval      time.Duration
+	flagFilePresent           bool
+	flagFileLastCheck         time.Time
+	flagFileMutex             sync.Mutex
+	adminSecret               string
+	adminPathPrefix           string
+	bypassIPs                 []*net.IPNet
+	trustedProxies            []*net.IPNet
+	bypassTokenSecret         []byte
+	bypassCookieName          string
+	bypassTokenHeader         string
+	stateURL                  string
+	statePollInterval         time.Duration
+	stateETag                 string
+	stateClient               *http.Client
+	readOnlyMethods           map[string]bool
+	passThroughPercent        int
+	passThroughKeySource      string
+	passThroughKeyName        string
+	circuitBreaker            *circuitBreaker
+	healthChecker             *healthChecker
+	problemDetails            *problemDetailsTemplate
+	now                       func() time.Time
 }
 
 // New creates a new MaintenanceBypass middleware.
@@ -141,36 +341,219 @@ func New(ctx context.Context, next http.Handler, config *Config, name string) (h
 		contentType = "text/html; charset=utf-8"
 	}
 
+	// Parse the maintenance schedule, if any
+	sched, err := newSchedule(config.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule: %w", err)
+	}
+
+	// Parse the expected maintenance end time, if any
+	var maintenanceEndTime time.Time
+	if config.MaintenanceEndTime != "" {
+		maintenanceEndTime, err = time.Parse(time.RFC3339, config.MaintenanceEndTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid maintenance end time: %w", err)
+		}
+	}
+
+	// Default Retry-After format and value if not specified
+	retryAfterFormat := config.RetryAfterFormat
+	if retryAfterFormat == "" {
+		retryAfterFormat = retryAfterFormatSeconds
+	}
+	if retryAfterFormat != retryAfterFormatSeconds && retryAfterFormat != retryAfterFormatHTTPDate {
+		return nil, fmt.Errorf("retryAfterFormat must be %q or %q", retryAfterFormatSeconds, retryAfterFormatHTTPDate)
+	}
+
+	defaultRetryAfter := config.DefaultRetryAfter
+	if defaultRetryAfter <= 0 {
+		defaultRetryAfter = 3600
+	}
+
+	// Default flag file poll interval if not specified
+	flagFilePollInterval := config.FlagFilePollInterval
+	if flagFilePollInterval <= 0 {
+		flagFilePollInterval = 5
+	}
+
+	// Default admin path prefix if not specified, always ending with a slash
+	adminPathPrefix := config.AdminPathPrefix
+	if adminPathPrefix == "" {
+		adminPathPrefix = "/.warden/"
+	}
+	if !strings.HasSuffix(adminPathPrefix, "/") {
+		adminPathPrefix += "/"
+	}
+
+	// Prepare the constant-time comparison of the bypass header value, preferring the hash if configured
+	bypassHeaderValue, err := newSecretMatcher(config.BypassHeaderValue, config.BypassHeaderValueHash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bypassHeaderValueHash: %w", err)
+	}
+
+	// Named bypass header rules replace the single bypass header
+	bypassHeaderRules, err := newBypassHeaderRules(config.BypassHeaderRules, config.BypassHeader, bypassHeaderValue)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bypassHeaderRules: %w", err)
+	}
+
+	// Compile the bypass path rules so that invalid patterns fail at startup
+	bypassPathRules, err := newPathRules(config.BypassPathRules)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bypassPathRules: %w", err)
+	}
+
+	// Parse the maintenance path groups
+	maintenancePaths, err := newMaintenancePathGroups(config.MaintenancePaths)
+	if err != nil {
+		return nil, fmt.Errorf("invalid maintenancePaths: %w", err)
+	}
+
+	// Parse the per-host configuration
+	hosts, err := newHostMatcher(config.Hosts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hosts: %w", err)
+	}
+
+	// Parse the bypass IP ranges and trusted proxies
+	bypassIPs, err := parseCIDRs(config.BypassIPs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bypassIPs: %w", err)
+	}
+
+	trustedProxies, err := parseCIDRs(config.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trustedProxies: %w", err)
+	}
+
+	// Default bypass cookie name if not specified
+	bypassCookieName := config.BypassCookieName
+	if bypassCookieName == "" {
+		bypassCookieName = "warden_bypass"
+	}
+
+	// Validate the remote state URL, if any
+	if config.StateURL != "" {
+		stateURL, err := url.Parse(config.StateURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid state URL: %w", err)
+		}
+
+		if stateURL.Scheme == "" || stateURL.Host == "" {
+			return nil, fmt.Errorf("state URL must include scheme and host")
+		}
+	}
+
+	// Default state poll interval if not specified
+	statePollInterval := config.StatePollInterval
+	if statePollInterval <= 0 {
+		statePollInterval = 10
+	}
+
+	// Validate the mode and the methods allowed in read-only mode
+	readOnlyMethods, err := newReadOnlyMethods(config.Mode, config.ReadOnlyAllowedMethods)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the gradual rollout configuration
+	if err := validatePassThrough(config.PassThroughPercent, config.PassThroughKeySource, config.PassThroughKeyName); err != nil {
+		return nil, err
+	}
+
+	// Set up automatic maintenance mode, if enabled
+	breaker, err := newCircuitBreaker(config.AutoTrigger)
+	if err != nil {
+		return nil, fmt.Errorf("invalid autoTrigger configuration: %w", err)
+	}
+
+	// Set up active health checking, if configured
+	checker, err := newHealthChecker(config.HealthCheck)
+	if err != nil {
+		return nil, fmt.Errorf("invalid healthCheck configuration: %w", err)
+	}
+
+	// Set up problem details responses, if enabled
+	problem, err := newProblemDetailsTemplate(config.ProblemDetails)
+	if err != nil {
+		return nil, fmt.Errorf("invalid problemDetails configuration: %w", err)
+	}
+
+	// Validate the JWT clock skew leeway
+	bypassJWTLeeway := config.BypassJWTLeeway
+	if bypassJWTLeeway < 0 {
+		return nil, fmt.Errorf("bypassJWTLeeway must not be negative, got %d", bypassJWTLeeway)
+	}
+
+	// Set up fetching of JWT verification keys from a JWKS URL, if configured
+	fetcher, err := newJWKSFetcher(config.BypassJWTJWKSURL, config.BypassJWTJWKSRefreshInterval, config.BypassJWTJWKSMinRefetchInterval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bypassJWTJWKSURL: %w", err)
+	}
+
 	// Create logger
 	logger := log.New(os.Stdout, "[maintenance-warden] ", log.LstdFlags)
 
 	// Create the middleware instance
 	m := &MaintenanceBypass{
-		next:                   next,
-		maintenanceFilePath:    config.MaintenanceFilePath,
-		maintenanceContent:     config.MaintenanceContent,
-		bypassHeader:           config.BypassHeader,
-		bypassHeaderValue:      config.BypassHeaderValue,
-		bypassJWTTokenHeader:   config.BypassJWTTokenHeader,
-		bypassJWTTokenClaim:    config.BypassJWTTokenClaim,
-		bypassJWTTokenClaimValue: config.BypassJWTTokenClaimValue,
-		enabled:                config.Enabled,
-		statusCode:             statusCode,
-		bypassPaths:            config.BypassPaths,
-		bypassFavicon:          config.BypassFavicon,
-		name:                   name,
-		logger:                 logger,
-		logLevel:               LogLevel(config.LogLevel),
-		contentType:            contentType,
-		timeout:                time.Duration(config.MaintenanceTimeout) * time.Second,
+		next:                      next,
+		maintenanceContent:        config.MaintenanceContent,
+		maintenanceJSONContent:    config.MaintenanceJSONContent,
+		maintenanceTextContent:    config.MaintenanceTextContent,
+		bypassHeaderRules:         bypassHeaderRules,
+		bypassJWTTokenHeader:      config.BypassJWTTokenHeader,
+		bypassJWTTokenClaim:       config.BypassJWTTokenClaim,
+		bypassJWTTokenClaimValues: jwtClaimValues(config.BypassJWTTokenClaimValue, config.BypassJWTTokenClaimValues),
+		bypassJWTSecret:           []byte(config.BypassJWTSecret),
+		enabled:                   config.Enabled,
+		statusCode:                statusCode,
+		bypassPaths:               config.BypassPaths,
+		bypassPathRules:           bypassPathRules,
+		maintenancePaths:          maintenancePaths,
+		hosts:                     hosts,
+		bypassFavicon:             config.BypassFavicon,
+		name:                      name,
+		logger:                    logger,
+		logLevel:                  LogLevel(config.LogLevel),
+		contentType:               contentType,
+		timeout:                   time.Duration(config.MaintenanceTimeout) * time.Second,
+		schedule:                  sched,
+		maintenanceEndTime:        maintenanceEndTime,
+		retryAfterFormat:          retryAfterFormat,
+		defaultRetryAfter:         time.Duration(defaultRetryAfter) * time.Second,
+		enabledFlagFile:           config.EnabledFlagFile,
+		flagFilePollInterval:      time.Duration(flagFilePollInterval) * time.Second,
+		adminSecret:               config.AdminSecret,
+		adminPathPrefix:           adminPathPrefix,
+		bypassIPs:                 bypassIPs,
+		trustedProxies:            trustedProxies,
+		bypassTokenSecret:         []byte(config.BypassTokenSecret),
+		bypassCookieName:          bypassCookieName,
+		bypassTokenHeader:         config.BypassTokenHeader,
+		stateURL:                  config.StateURL,
+		statePollInterval:         time.Duration(statePollInterval) * time.Second,
+		readOnlyMethods:           readOnlyMethods,
+		passThroughPercent:        config.PassThroughPercent,
+		passThroughKeySource:      config.PassThroughKeySource,
+		passThroughKeyName:        config.PassThroughKeyName,
+		circuitBreaker:            breaker,
+		healthChecker:             checker,
+		problemDetails:            problem,
+		jwksFetcher:               fetcher,
+		bypassJWTLeeway:           time.Duration(bypassJWTLeeway) * time.Second,
+		bypassJWTIssuer:           config.BypassJWTIssuer,
+		bypassJWTAudience:         config.BypassJWTAudience,
+		now:                       time.Now,
 	}
 
 	// If maintenance file path is specified, try to read it initially
 	if config.MaintenanceFilePath != "" {
-		err := m.loadMaintenanceFile()
+		m.maintenanceFile = &maintenanceFile{path: config.MaintenanceFilePath}
+		content, err := m.maintenanceFile.load()
 		if err != nil {
 			return nil, fmt.Errorf("failed to load maintenance file: %w", err)
 		}
+		m.log(LogLevelInfo, "Loaded maintenance file: %s (%d bytes)", config.MaintenanceFilePath, len(content))
 	} else if config.MaintenanceContent != "" {
 		// If direct content is provided, use that
 		m.log(LogLevelInfo, "Using provided maintenance content (%d bytes)", len(config.MaintenanceContent))
@@ -191,43 +574,82 @@ func New(ctx context.Context, next http.Handler, config *Config, name string) (h
 		}
 
 		m.maintenanceService = maintenanceURL
-	} else {
+	} else if m.problemDetails != nil {
+		// Problem details are generated from the configuration
+		m.log(LogLevelInfo, "Using problem details as maintenance content")
+	} else if !m.maintenancePathsHaveSources() {
 		return nil, fmt.Errorf("either maintenanceService, maintenanceFilePath, or maintenanceContent must be specified")
 	}
 
-	return m, nil
-}
+	// Maintenance services of path groups and hosts use the same timeout as the top-level service
+	if m.timeout == 0 {
+		m.timeout = 10 * time.Second
+	}
 
-// loadMaintenanceFile reads the maintenance HTML file from disk
-func (m *MaintenanceBypass) loadMaintenanceFile() error {
-	m.fileMutex.Lock()
-	defer m.fileMutex.Unlock()
+	// Load the JWT verification keys initially so that invalid key files fail at startup
+	if config.BypassJWTPublicKeyFile != "" {
+		m.jwtPublicKeyFile = &jwtKeyFile{path: config.BypassJWTPublicKeyFile, parse: parsePEMKeys}
+		if _, err := m.jwtPublicKeyFile.load(); err != nil {
+			return nil, fmt.Errorf("failed to load JWT public key file: %w", err)
+		}
+	}
 
-	fileInfo, err := os.Stat(m.maintenanceFilePath)
-	if err != nil {
-		return fmt.Errorf("error accessing maintenance file: %w", err)
+	if config.BypassJWTJWKSFile != "" {
+		m.jwtJWKSFile = &jwtKeyFile{path: config.BypassJWTJWKSFile, parse: parseJWKS}
+		if _, err := m.jwtJWKSFile.load(); err != nil {
+			return nil, fmt.Errorf("failed to load JWT JWKS file: %w", err)
+		}
 	}
 
-	// Only reload if file is newer than our last modification time
-	if m.maintenanceFileContent != nil && !fileInfo.ModTime().After(m.maintenanceFileLastMod) {
-		return nil
+	// JWT claims are only trusted from tokens whose signature can be verified
+	if m.bypassJWTTokenClaim != "" && !m.jwtVerificationConfigured() {
+		return nil, fmt.Errorf("bypassJWTTokenClaim requires bypassJWTSecret, bypassJWTPublicKeyFile, bypassJWTJWKSFile or bypassJWTJWKSURL to verify token signatures")
 	}
 
-	content, err := ioutil.ReadFile(m.maintenanceFilePath)
-	if err != nil {
-		return fmt.Errorf("error reading maintenance file: %w", err)
+	// Poll the remote state in the background, starting from the configured enabled flag
+	if m.stateURL != "" {
+		m.stateClient = &http.Client{Timeout: m.statePollInterval}
+		go m.watchRemoteState(ctx)
+	}
+
+	// Check the backend health in the background until Traefik discards the middleware
+	if m.healthChecker != nil {
+		go m.watchHealth(ctx)
+	}
+
+	// Refresh the JWKS in the background until Traefik discards the middleware
+	if m.jwksFetcher != nil {
+		go m.watchJWKS(ctx)
+	}
+
+	return m, nil
+}
+
+// isFlagFilePresent reports whether the enabled flag file exists.
+// The result is cached and the file is only checked again once the poll interval has elapsed.
+func (m *MaintenanceBypass) isFlagFilePresent(now time.Time) bool {
+	m.flagFileMutex.Lock()
+	defer m.flagFileMutex.Unlock()
+
+	// Only check again if the cached result is older than the poll interval
+	if !m.flagFileLastCheck.IsZero() && now.Sub(m.flagFileLastCheck) < m.flagFilePollInterval {
+		return m.flagFilePresent
+	}
+
+	_, err := os.Stat(m.enabledFlagFile)
+	present := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		m.log(LogLevelError, "Error accessing enabled flag file: %v", err)
 	}
 
-	// Check if the file is empty
-	if len(content) == 0 {
-		return fmt.Errorf("maintenance file is empty: %s", m.maintenanceFilePath)
+	if present != m.flagFilePresent || m.flagFileLastCheck.IsZero() {
+		m.log(LogLevelInfo, "Enabled flag file %s present: %t", m.enabledFlagFile, present)
 	}
 
-	m.maintenanceFileContent = content
-	m.maintenanceFileLastMod = fileInfo.ModTime()
-	m.log(LogLevelInfo, "Loaded maintenance file: %s (%d bytes)", m.maintenanceFilePath, len(content))
+	m.flagFilePresent = present
+	m.flagFileLastCheck = now
 
-	return nil
+	return present
 }
 
 // log logs a message at the specified level
@@ -237,25 +659,181 @@ func (m *MaintenanceBypass) log(level LogLevel, format string, v ...interface{})
 	}
 }
 
+// currentTime returns the current time from the injectable clock, defaulting to time.Now
+func (m *MaintenanceBypass) currentTime() time.Time {
+	if m.now != nil {
+		return m.now()
+	}
+	return time.Now()
+}
+
 // isMaintenanceEnabled checks if maintenance mode is enabled for this request
 // taking into account both the static configuration and any dynamic annotation
 func (m *MaintenanceBypass) isMaintenanceEnabled(req *http.Request) bool {
-	// No annotation control or no match, use the static configuration
-	return m.enabled
+	m.stateMutex.RLock()
+	enabled, forceEnabled, sched := m.enabled, m.forceEnabled, m.schedule
+	m.stateMutex.RUnlock()
+
+	// The enabled flag of the requested host replaces the top-level flag
+	if host := m.hostFor(req); host != nil && host.enabled != nil {
+		enabled = *host.enabled
+	}
+
+	// The enabled flag acts as a master switch
+	if !enabled {
+		return false
+	}
+
+	// Without any activation source, or when forced on at runtime, the enabled flag decides
+	if forceEnabled || (sched == nil && m.enabledFlagFile == "") {
+		return true
+	}
+
+	now := m.currentTime()
+
+	// Maintenance is active inside a scheduled window
+	if sched != nil {
+		if _, active := sched.activeWindow(now); active {
+			return true
+		}
+	}
+
+	// Maintenance is active while the flag file exists
+	if m.enabledFlagFile != "" && m.isFlagFilePresent(now) {
+		return true
+	}
+
+	return false
+}
+
+// maintenanceEnd returns when the current maintenance is expected to end, if known.
+// An active scheduled window takes precedence over the configured maintenance end time.
+func (m *MaintenanceBypass) maintenanceEnd(now time.Time) (time.Time, bool) {
+	m.stateMutex.RLock()
+	sched, maintenanceEndTime := m.schedule, m.maintenanceEndTime
+	m.stateMutex.RUnlock()
+
+	if sched != nil {
+		if window, active := sched.activeWindow(now); active {
+			return window.end, true
+		}
+	}
+
+	if !maintenanceEndTime.IsZero() && maintenanceEndTime.After(now) {
+		return maintenanceEndTime, true
+	}
+
+	// When maintenance was triggered automatically, recovery is probed after the cooldown
+	if m.circuitBreaker != nil {
+		if retryAt, open := m.circuitBreaker.retryAt(); open && retryAt.After(now) {
+			return retryAt, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// retryTime returns when clients should retry, which is the expected end of maintenance if known
+func (m *MaintenanceBypass) retryTime(now time.Time) time.Time {
+	end, ok := m.maintenanceEnd(now)
+	if !ok {
+		end = now.Add(m.defaultRetryAfter)
+	}
+	return end
+}
+
+// retryAfterSeconds returns the number of seconds until clients should retry
+func (m *MaintenanceBypass) retryAfterSeconds(now time.Time) int64 {
+	end := m.retryTime(now)
+
+	// Round up so clients never retry before maintenance ends
+	seconds := int64(end.Sub(now) / time.Second)
+	if end.Sub(now)%time.Second != 0 {
+		seconds++
+	}
+
+	return seconds
+}
+
+// retryAfter returns the Retry-After header value for a maintenance response
+func (m *MaintenanceBypass) retryAfter(now time.Time) string {
+	if m.retryAfterFormat == retryAfterFormatHTTPDate {
+		return m.retryTime(now).UTC().Format(http.TimeFormat)
+	}
+
+	return fmt.Sprintf("%d", m.retryAfterSeconds(now))
 }
 
 // ServeHTTP implements the http.Handler interface.
 func (m *MaintenanceBypass) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	// The bypass link exchanges a signed token for a bypass cookie
+	if len(m.bypassTokenSecret) > 0 && req.URL.Path == m.adminPathPrefix+"bypass" {
+		m.serveBypassLink(rw, req)
+		return
+	}
+
+	// Requests under the reserved admin prefix are handled by the admin API
+	if m.adminSecret != "" && strings.HasPrefix(req.URL.Path, m.adminPathPrefix) {
+		m.serveAdmin(rw, req)
+		return
+	}
+
+	// Hosts can replace the top-level bypass settings and maintenance page
+	host := m.hostFor(req)
+	bypassPaths, bypassPathRules := m.bypassPaths, m.bypassPathRules
+	if host != nil {
+		if host.bypassPaths != nil {
+			bypassPaths = host.bypassPaths
+		}
+		if host.bypassPathRules != nil {
+			bypassPathRules = host.bypassPathRules
+		}
+	}
+
 	// Check if maintenance mode is enabled, considering annotations if configured
 	enabled := m.isMaintenanceEnabled(req)
-	
+
+	// An unhealthy backend turns maintenance mode on
+	if !enabled && m.healthChecker != nil && !m.healthChecker.isHealthy() {
+		m.log(LogLevelDebug, "Backend is unhealthy, maintenance mode is active for %s", req.URL.String())
+		enabled = true
+	}
+
 	// If maintenance mode is disabled, simply pass to the next handler
-	if !enabled {
+	if !enabled && m.circuitBreaker == nil {
 		m.log(LogLevelDebug, "Maintenance mode is disabled, passing request through: %s", req.URL.String())
 		m.next.ServeHTTP(rw, req)
 		return
 	}
 
+	// Otherwise the circuit breaker decides, falling through to maintenance while it is open
+	if !enabled {
+		if allowed, probe := m.circuitBreaker.allow(m.currentTime()); allowed {
+			m.log(LogLevelDebug, "Maintenance mode is disabled, passing request through (probe=%t): %s", probe, req.URL.String())
+			m.serveNextWithBreaker(rw, req, probe)
+			return
+		}
+		m.log(LogLevelDebug, "Automatic maintenance is active for %s", req.URL.String())
+	}
+
+	// In read-only mode, safe methods pass through
+	if m.readOnlyMethods != nil && m.readOnlyMethods[req.Method] {
+		m.log(LogLevelDebug, "Read-only mode allows %s requests, passing through: %s", req.Method, req.URL.String())
+		m.next.ServeHTTP(rw, req)
+		return
+	}
+
+	// With maintenance paths, only matching requests get the maintenance page
+	var group *maintenancePathGroup
+	if len(m.maintenancePaths) > 0 {
+		var ok bool
+		if group, ok = m.matchMaintenancePath(req.URL.Path); !ok {
+			m.log(LogLevelDebug, "Request path %s is not under maintenance, passing through", req.URL.Path)
+			m.next.ServeHTTP(rw, req)
+			return
+		}
+	}
+
 	// Check if the request is for favicon.ico and should bypass
 	if m.bypassFavicon && strings.HasSuffix(req.URL.Path, "/favicon.ico") {
 		m.log(LogLevelDebug, "Request is for favicon.ico, bypassing maintenance mode: %s", req.URL.String())
@@ -264,7 +842,7 @@ func (m *MaintenanceBypass) ServeHTTP(rw http.ResponseWriter, req *http.Request)
 	}
 
 	// Check if the request path is in the bypass paths list
-	for _, path := range m.bypassPaths {
+	for _, path := range bypassPaths {
 		if strings.HasPrefix(req.URL.Path, path) {
 			m.log(LogLevelDebug, "Request path %s matches bypass path %s, passing through", req.URL.Path, path)
 			m.next.ServeHTTP(rw, req)
@@ -272,21 +850,38 @@ func (m *MaintenanceBypass) ServeHTTP(rw http.ResponseWriter, req *http.Request)
 		}
 	}
 
-	// Check if the request has the bypass header with the correct value
-	// Only check if bypassHeader is configured
-	if m.bypassHeader != "" {
-		headerValue := req.Header.Get(m.bypassHeader)
-		if headerValue == m.bypassHeaderValue {
-			// If the bypass header is present with the correct value, pass the request to the next handler
-			m.log(LogLevelDebug, "Bypass header found with value %s, passing to next handler", headerValue)
-			m.next.ServeHTTP(rw, req)
-			return
-		}
+	// Check if the request path matches one of the bypass path rules
+	if rule, ok := matchPathRule(bypassPathRules, req.URL.Path); ok {
+		m.log(LogLevelDebug, "Request path %s matches bypass path rule %s, passing through", req.URL.Path, rule)
+		m.next.ServeHTTP(rw, req)
+		return
 	}
-	
+
+	// Check if the client IP is in one of the bypass ranges
+	if ip, ok := m.isBypassIP(req); ok {
+		m.log(LogLevelDebug, "Client IP %s matches a bypass range, passing to next handler", ip)
+		m.next.ServeHTTP(rw, req)
+		return
+	}
+
+	// Check if the request has a bypass header with an accepted value
+	if rule, ok := m.matchBypassHeaderRule(req); ok {
+		// If a bypass header rule matches, pass the request to the next handler
+		m.log(LogLevelDebug, "Bypass header rule %q matched, passing to next handler", rule)
+		m.next.ServeHTTP(rw, req)
+		return
+	}
+
+	// Check if the request carries a valid bypass cookie issued by the bypass link or a signed bypass token
+	if m.hasBypassCookie(req) || m.hasBypassTokenHeader(req) {
+		m.log(LogLevelDebug, "Valid bypass token found, passing to next handler")
+		m.next.ServeHTTP(rw, req)
+		return
+	}
+
 	// Check if JWT token has the bypass claim with the correct value
 	// Only check if bypassJWTTokenHeader and bypassJWTTokenClaim are configured
-	if m.bypassJWTTokenHeader != "" && m.bypassJWTTokenClaim != "" && m.bypassJWTTokenClaimValue != "" {
+	if m.bypassJWTTokenHeader != "" && m.bypassJWTTokenClaim != "" && len(m.bypassJWTTokenClaimValues) > 0 {
 		// Get the JWT token from the header
 		authHeader := req.Header.Get(m.bypassJWTTokenHeader)
 		if authHeader != "" {
@@ -295,12 +890,13 @@ func (m *MaintenanceBypass) ServeHTTP(rw http.ResponseWriter, req *http.Request)
 			if strings.HasPrefix(strings.ToLower(authHeader), "bearer ") {
 				tokenString = authHeader[7:]
 			}
-			
-			// Parse and validate the JWT token
-			claimValue, err := m.getJWTClaimValue(tokenString, m.bypassJWTTokenClaim)
-			if err != nil {
+
+			// Verify the JWT token before trusting any of its claims
+			if err := m.verifyJWT(req.Context(), tokenString); err != nil {
+				m.log(LogLevelDebug, "JWT token rejected: %v", err)
+			} else if claimValues, err := m.getJWTClaimValues(tokenString, m.bypassJWTTokenClaim); err != nil {
 				m.log(LogLevelDebug, "Error parsing JWT token: %v", err)
-			} else if claimValue == m.bypassJWTTokenClaimValue {
+			} else if claimValue, ok := m.matchJWTClaimValue(claimValues); ok {
 				// If JWT token has the bypass claim with the correct value, pass the request to the next handler
 				m.log(LogLevelDebug, "JWT token bypass claim found with value %s, passing to next handler", claimValue)
 				m.next.ServeHTTP(rw, req)
@@ -309,25 +905,59 @@ func (m *MaintenanceBypass) ServeHTTP(rw http.ResponseWriter, req *http.Request)
 		}
 	}
 
+	// Let the configured share of users through during a gradual rollout
+	if m.isPassThroughSelected(req) {
+		m.log(LogLevelDebug, "Request selected for %d%% pass-through, passing to next handler: %s", m.passThroughPercent, req.URL.String())
+		m.next.ServeHTTP(rw, req)
+		return
+	}
+
 	// No bypass condition met, serve the maintenance page
 	m.log(LogLevelInfo, "Serving maintenance page for %s", req.URL.String())
 
 	// Set all common maintenance-related headers here
 	rw.Header().Set("X-Maintenance-Mode", "true")
 	rw.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-	rw.Header().Set("Retry-After", "3600") // Suggest client retry after 1 hour
-	rw.Header().Set("Content-Type", m.contentType)
-	
+	rw.Header().Set("Retry-After", m.retryAfter(m.currentTime()))
+
+	// The matched host and maintenance path group can set their own status code, content type and content
+	if host != nil {
+		m.log(LogLevelDebug, "Request host %s matches host %q", req.Host, host.pattern)
+	}
+	if group != nil {
+		m.log(LogLevelDebug, "Request path %s matches maintenance path group %q", req.URL.Path, group.name)
+	}
+	source := m.resolveMaintenanceSource(host, group)
+	rw.Header().Set("Content-Type", source.contentType)
+	if source.statusCode != m.statusCode {
+		rw = &maintenanceResponseWriter{ResponseWriter: rw, statusCode: source.statusCode}
+	}
+
+	// Clients preferring JSON or plain text get those instead of an HTML page
+	alternativeType := m.alternativeMaintenanceType(rw, req, source.contentType)
+
 	// Determine which maintenance content to serve
-	if m.maintenanceContent != "" {
+	if m.readOnlyMethods != nil && isAPIRequest(req) {
+		// API clients get a JSON error for blocked writes in read-only mode
+		m.serveReadOnlyError(rw, req, source.statusCode)
+	} else if alternativeType != "" {
+		// If the client prefers JSON or plain text, serve that
+		m.serveAlternativeContent(rw, req, alternativeType, source.statusCode)
+	} else if source.hasSource() {
+		// If the host or maintenance path group has its own content, serve that
+		m.serveMaintenanceSource(rw, req, &source)
+	} else if m.maintenanceContent != "" {
 		// If inline content is provided, serve that
 		m.serveMaintenanceContent(rw, req)
-	} else if m.maintenanceFilePath != "" {
+	} else if m.maintenanceFile != nil {
 		// If a file path is provided, serve the file
 		m.serveMaintenanceFile(rw, req)
 	} else if m.maintenanceService != nil {
 		// If a maintenance service is configured, proxy to it
 		m.proxyToMaintenanceService(rw, req)
+	} else if m.problemDetails != nil {
+		// If problem details are enabled, serve those
+		m.serveProblemDetails(rw, req, source.statusCode, maintenanceMessage)
 	} else {
 		// This should never happen as the configuration is validated in New()
 		rw.WriteHeader(m.statusCode)
@@ -338,18 +968,15 @@ func (m *MaintenanceBypass) ServeHTTP(rw http.ResponseWriter, req *http.Request)
 // serveMaintenanceFile serves the static maintenance file
 func (m *MaintenanceBypass) serveMaintenanceFile(rw http.ResponseWriter, req *http.Request) {
 	// Try to reload the file if it's changed (check file modification time)
-	err := m.loadMaintenanceFile()
+	content, err := m.maintenanceFile.load()
 	if err != nil {
 		m.log(LogLevelError, "Failed to load maintenance file: %v", err)
-		http.Error(rw, "Service Temporarily Unavailable", m.statusCode)
-		return
+		if content == nil {
+			http.Error(rw, "Service Temporarily Unavailable", m.statusCode)
+			return
+		}
 	}
 
-	// Read the content from our cache
-	m.fileMutex.RLock()
-	content := m.maintenanceFileContent
-	m.fileMutex.RUnlock()
-
 	// Write the status code and content
 	rw.WriteHeader(m.statusCode)
 	rw.Write(content)
@@ -359,7 +986,7 @@ func (m *MaintenanceBypass) serveMaintenanceFile(rw http.ResponseWriter, req *ht
 func (m *MaintenanceBypass) serveMaintenanceContent(rw http.ResponseWriter, req *http.Request) {
 	// Set the status code
 	rw.WriteHeader(m.statusCode)
-	
+
 	// Write the content
 	_, err := rw.Write([]byte(m.maintenanceContent))
 	if err != nil {
@@ -369,14 +996,19 @@ func (m *MaintenanceBypass) serveMaintenanceContent(rw http.ResponseWriter, req
 
 // proxyToMaintenanceService proxies the request to the maintenance service
 func (m *MaintenanceBypass) proxyToMaintenanceService(rw http.ResponseWriter, req *http.Request) {
+	m.proxyToService(rw, req, m.maintenanceService, m.statusCode)
+}
+
+// proxyToService proxies the request to a maintenance service, responding with the given status code
+func (m *MaintenanceBypass) proxyToService(rw http.ResponseWriter, req *http.Request, service *url.URL, statusCode int) {
 	// Create a custom response writer that will set our status code
 	maintenanceWriter := &maintenanceResponseWriter{
 		ResponseWriter: rw,
-		statusCode:     m.statusCode,
+		statusCode:     statusCode,
 	}
 
 	// Create a reverse proxy to the maintenance service
-	proxy := httputil.NewSingleHostReverseProxy(m.maintenanceService)
+	proxy := httputil.NewSingleHostReverseProxy(service)
 
 	// Set a timeout for the proxy
 	proxy.Transport = &http.Transport{
@@ -387,7 +1019,7 @@ func (m *MaintenanceBypass) proxyToMaintenanceService(rw http.ResponseWriter, re
 	proxy.ErrorHandler = func(rw http.ResponseWriter, req *http.Request, err error) {
 		m.log(LogLevelError, "Error proxying to maintenance service: %v", err)
 		// Don't need to set X-Maintenance-Mode here since it's already set in ServeHTTP
-		rw.WriteHeader(m.statusCode)
+		rw.WriteHeader(statusCode)
 		rw.Write([]byte("Service temporarily unavailable"))
 	}
 
@@ -395,9 +1027,9 @@ func (m *MaintenanceBypass) proxyToMaintenanceService(rw http.ResponseWriter, re
 	proxyReq := req.Clone(req.Context())
 
 	// Update the cloned request Host to match the maintenance service
-	proxyReq.URL.Host = m.maintenanceService.Host
-	proxyReq.URL.Scheme = m.maintenanceService.Scheme
-	proxyReq.Host = m.maintenanceService.Host
+	proxyReq.URL.Host = service.Host
+	proxyReq.URL.Scheme = service.Scheme
+	proxyReq.Host = service.Host
 
 	// Proxy the request to the maintenance service with our custom writer
 	proxy.ServeHTTP(maintenanceWriter, proxyReq)
@@ -425,41 +1057,3 @@ func (w *maintenanceResponseWriter) Write(b []byte) (int, error) {
 	}
 	return w.ResponseWriter.Write(b)
 }
-
-// getJWTClaimValue extracts a claim value from a JWT token
-func (m *MaintenanceBypass) getJWTClaimValue(tokenString string, claimName string) (string, error) {
-	// Split the token into parts
-	parts := strings.Split(tokenString, ".")
-	if len(parts) != 3 {
-		return "", fmt.Errorf("invalid JWT token format")
-	}
-
-	// Decode the payload (second part)
-	payloadBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
-	if err != nil {
-		return "", fmt.Errorf("error decoding JWT payload: %w", err)
-	}
-
-	// Parse the payload
-	var claims map[string]interface{}
-	if err := json.Unmarshal(payloadBytes, &claims); err != nil {
-		return "", fmt.Errorf("error parsing JWT claims: %w", err)
-	}
-
-	// Extract the claim value
-	if value, ok := claims[claimName]; ok {
-		// Convert claim value to string
-		switch v := value.(type) {
-		case string:
-			return v, nil
-		case float64:
-			return fmt.Sprintf("%g", v), nil
-		case bool:
-			return fmt.Sprintf("%t", v), nil
-		default:
-			return fmt.Sprintf("%v", v), nil
-		}
-	}
-
-	return "", fmt.Errorf("claim %s not found in JWT token", claimName)
-}
@@ -0,0 +1,93 @@
+package traefik_maintenance_warden
+
+import (
+	"fmt"
+)
+
+// MaintenancePathConfig configures a group of paths that are under maintenance
+type MaintenancePathConfig struct {
+	// Name identifies the group in logs
+	Name string `json:"name,omitempty"`
+
+	// Paths are the path rules selecting the requests of the group
+	Paths []PathRuleConfig `json:"paths,omitempty"`
+
+	// StatusCode is the HTTP status code returned for the group, defaulting to the top-level status code
+	StatusCode int `json:"statusCode,omitempty"`
+
+	// MaintenanceService is the URL of the maintenance service for the group
+	MaintenanceService string `json:"maintenanceService,omitempty"`
+
+	// MaintenanceFilePath is the path to a static file served for the group
+	MaintenanceFilePath string `json:"maintenanceFilePath,omitempty"`
+
+	// MaintenanceContent is the direct content served for the group
+	MaintenanceContent string `json:"maintenanceContent,omitempty"`
+
+	// ContentType is the content type of the group's maintenance file or content, defaulting to the top-level content type
+	ContentType string `json:"contentType,omitempty"`
+}
+
+// maintenancePathGroup is a parsed group of paths under maintenance
+type maintenancePathGroup struct {
+	maintenanceSource
+	name  string
+	rules []pathRule
+}
+
+// newMaintenancePathGroups parses the maintenance path groups, loading their files so that
+// missing files fail at startup
+func newMaintenancePathGroups(configs []MaintenancePathConfig) ([]maintenancePathGroup, error) {
+	groups := make([]maintenancePathGroup, 0, len(configs))
+
+	for i, config := range configs {
+		name := config.Name
+		if name == "" {
+			name = fmt.Sprintf("%d", i)
+		}
+
+		if len(config.Paths) == 0 {
+			return nil, fmt.Errorf("group %q must have at least one path", name)
+		}
+
+		rules, err := newPathRules(config.Paths)
+		if err != nil {
+			return nil, fmt.Errorf("group %q: %w", name, err)
+		}
+
+		source, err := newMaintenanceSource(config.StatusCode, config.ContentType,
+			config.MaintenanceContent, config.MaintenanceFilePath, config.MaintenanceService)
+		if err != nil {
+			return nil, fmt.Errorf("group %q: %w", name, err)
+		}
+
+		group := maintenancePathGroup{maintenanceSource: source, name: name, rules: rules}
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+// matchMaintenancePath returns the first maintenance path group with a rule matching the request path
+func (m *MaintenanceBypass) matchMaintenancePath(requestPath string) (*maintenancePathGroup, bool) {
+	for i := range m.maintenancePaths {
+		if _, ok := matchPathRule(m.maintenancePaths[i].rules, requestPath); ok {
+			return &m.maintenancePaths[i], true
+		}
+	}
+	return nil, false
+}
+
+// maintenancePathsHaveSources reports whether every maintenance path group has a content source of its own,
+// in which case no top-level content source is needed
+func (m *MaintenanceBypass) maintenancePathsHaveSources() bool {
+	if len(m.maintenancePaths) == 0 {
+		return false
+	}
+	for i := range m.maintenancePaths {
+		if !m.maintenancePaths[i].hasSource() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,259 @@
+package traefik_maintenance_warden
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMaintenancePaths tests that only requests under maintenance paths get the maintenance page
+func TestMaintenancePaths(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "maintenance-paths-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	filePath := filepath.Join(tmpDir, "payments.json")
+	if err := ioutil.WriteFile(filePath, []byte(`{"error":"payments_maintenance"}`), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	service := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte("Search is down"))
+	}))
+	defer service.Close()
+
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+		rw.Write([]byte("Backend"))
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		ContentType:        "text/html; charset=utf-8",
+		Enabled:            true,
+		BypassHeader:       "X-Maintenance-Bypass",
+		BypassHeaderValue:  "true",
+		MaintenancePaths: []MaintenancePathConfig{
+			{
+				Name:  "checkout",
+				Paths: []PathRuleConfig{{Match: "segment-prefix", Path: "/checkout"}},
+			},
+			{
+				Name:                "payments",
+				Paths:               []PathRuleConfig{{Match: "segment-prefix", Path: "/api/v2/payments"}},
+				StatusCode:          http.StatusBadGateway,
+				MaintenanceFilePath: filePath,
+				ContentType:         "application/json",
+			},
+			{
+				Name:               "reports",
+				Paths:              []PathRuleConfig{{Match: "glob", Path: "/reports/*.pdf"}},
+				StatusCode:         http.StatusTooManyRequests,
+				MaintenanceContent: "Reports are paused",
+				ContentType:        "text/plain",
+			},
+			{
+				Name:               "search",
+				Paths:              []PathRuleConfig{{Match: "exact", Path: "/search"}},
+				MaintenanceService: service.URL,
+			},
+		},
+	}, "maintenance-paths-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	testCases := []struct {
+		name                string
+		path                string
+		bypass              bool
+		expectedCode        int
+		expectedBody        string
+		expectedContentType string
+	}{
+		{"Outside maintenance paths", "/catalog", false, http.StatusOK, "Backend", ""},
+		{"Sibling of maintenance path", "/checkout-help", false, http.StatusOK, "Backend", ""},
+		{"Top-level content", "/checkout/cart", false, http.StatusServiceUnavailable, "<html><body>Maintenance</body></html>", "text/html; charset=utf-8"},
+		{"Bypass still applies", "/checkout/cart", true, http.StatusOK, "Backend", ""},
+		{"Group file and status code", "/api/v2/payments/42", false, http.StatusBadGateway, `{"error":"payments_maintenance"}`, "application/json"},
+		{"Group content and status code", "/reports/q1.pdf", false, http.StatusTooManyRequests, "Reports are paused", "text/plain"},
+		{"Group service", "/search", false, http.StatusServiceUnavailable, "Search is down", "text/html; charset=utf-8"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com"+tc.path, nil)
+			if tc.bypass {
+				req.Header.Set("X-Maintenance-Bypass", "true")
+			}
+			recorder := httptest.NewRecorder()
+			middleware.ServeHTTP(recorder, req)
+
+			if recorder.Code != tc.expectedCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedCode, recorder.Code)
+			}
+
+			if body := recorder.Body.String(); body != tc.expectedBody {
+				t.Errorf("Expected body %q, got %q", tc.expectedBody, body)
+			}
+
+			if tc.expectedContentType != "" && recorder.Header().Get("Content-Type") != tc.expectedContentType {
+				t.Errorf("Expected content type %q, got %q", tc.expectedContentType, recorder.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+// TestMaintenancePathsWithoutTopLevelSource tests that groups with their own content do not need a top-level source
+func TestMaintenancePathsWithoutTopLevelSource(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	_, err := New(context.Background(), nextHandler, &Config{
+		Enabled: true,
+		MaintenancePaths: []MaintenancePathConfig{{
+			Paths:              []PathRuleConfig{{Match: "prefix", Path: "/checkout"}},
+			MaintenanceContent: "Checkout is down",
+		}},
+	}, "maintenance-paths-test")
+	if err != nil {
+		t.Errorf("Expected groups with their own content to be enough, got %v", err)
+	}
+
+	_, err = New(context.Background(), nextHandler, &Config{
+		Enabled: true,
+		MaintenancePaths: []MaintenancePathConfig{{
+			Paths: []PathRuleConfig{{Match: "prefix", Path: "/checkout"}},
+		}},
+	}, "maintenance-paths-test")
+	if err == nil {
+		t.Errorf("Expected an error when a group relies on a missing top-level source")
+	}
+}
+
+// TestMaintenancePathFileErrors tests the response of a group whose maintenance file cannot be served
+func TestMaintenancePathFileErrors(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "maintenance-paths-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	filePath := filepath.Join(tmpDir, "checkout.html")
+	if err := ioutil.WriteFile(filePath, []byte("Checkout is down"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	logWriter := &testLogWriter{}
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		Enabled:  true,
+		LogLevel: int(LogLevelError),
+		MaintenancePaths: []MaintenancePathConfig{
+			{
+				Paths:               []PathRuleConfig{{Match: "prefix", Path: "/checkout"}},
+				StatusCode:          http.StatusBadGateway,
+				MaintenanceFilePath: filePath,
+			},
+			{
+				Paths:              []PathRuleConfig{{Match: "prefix", Path: "/reports"}},
+				MaintenanceContent: "Reports are paused",
+			},
+		},
+	}, "maintenance-paths-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	m := middleware.(*MaintenanceBypass)
+	m.logger = log.New(logWriter, "", 0)
+
+	// A file emptied after startup keeps the previous page
+	if err := ioutil.WriteFile(filePath, nil, 0644); err != nil {
+		t.Fatalf("Failed to empty test file: %v", err)
+	}
+	modTime := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+		t.Fatalf("Failed to update test file time: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	m.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://example.com/checkout", nil))
+
+	if recorder.Body.String() != "Checkout is down" {
+		t.Errorf("Expected the previous page after an empty update, got %q", recorder.Body.String())
+	}
+
+	// A file deleted after startup falls back to a plain error with the group's status code
+	if err := os.Remove(filePath); err != nil {
+		t.Fatalf("Failed to remove test file: %v", err)
+	}
+
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://example.com/checkout", nil))
+
+	if recorder.Code != http.StatusBadGateway {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadGateway, recorder.Code)
+	}
+	if !strings.Contains(recorder.Body.String(), "Service Temporarily Unavailable") {
+		t.Errorf("Expected fallback error body, got %q", recorder.Body.String())
+	}
+	if !strings.Contains(logWriter.String(), "Failed to load maintenance file") {
+		t.Errorf("Expected error log about loading the maintenance file, got: %s", logWriter.String())
+	}
+
+	// Errors writing group content are logged
+	m.ServeHTTP(&MockErrorResponseWriter{}, httptest.NewRequest(http.MethodGet, "http://example.com/reports", nil))
+	if !strings.Contains(logWriter.String(), "Error writing maintenance content") {
+		t.Errorf("Expected error log about writing maintenance content, got: %s", logWriter.String())
+	}
+}
+
+// TestMaintenancePathsValidation tests validation of the maintenance path groups
+func TestMaintenancePathsValidation(t *testing.T) {
+	checkout := []PathRuleConfig{{Match: "prefix", Path: "/checkout"}}
+
+	emptyFile := filepath.Join(t.TempDir(), "empty.html")
+	if err := ioutil.WriteFile(emptyFile, nil, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	testCases := []struct {
+		name   string
+		groups []MaintenancePathConfig
+	}{
+		{"No paths", []MaintenancePathConfig{{Name: "checkout"}}},
+		{"Invalid path rule", []MaintenancePathConfig{{Paths: []PathRuleConfig{{Match: "regex", Path: "("}}}}},
+		{"Multiple sources", []MaintenancePathConfig{{Paths: checkout, MaintenanceContent: "a", MaintenanceService: "http://maintenance"}}},
+		{"Invalid service", []MaintenancePathConfig{{Paths: checkout, MaintenanceService: "maintenance"}}},
+		{"Missing file", []MaintenancePathConfig{{Paths: checkout, MaintenanceFilePath: "/nonexistent/file.html"}}},
+		{"Empty file", []MaintenancePathConfig{{Paths: checkout, MaintenanceFilePath: emptyFile}}},
+		{"Directory as file", []MaintenancePathConfig{{Paths: checkout, MaintenanceFilePath: filepath.Dir(emptyFile)}}},
+		{"Unparsable service URL", []MaintenancePathConfig{{Paths: checkout, MaintenanceService: "http://[::1"}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(context.Background(), nil, &Config{
+				MaintenanceContent: "<html><body>Maintenance</body></html>",
+				MaintenancePaths:   tc.groups,
+			}, "maintenance-paths-test")
+			if err == nil {
+				t.Errorf("Expected an error but got none")
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package traefik_maintenance_warden
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// maintenanceSource replaces parts of the top-level maintenance response for a subset of requests.
+// Zero values inherit the top-level settings.
+type maintenanceSource struct {
+	statusCode  int
+	contentType string
+	content     string
+	file        *maintenanceFile
+	service     *url.URL
+}
+
+// newMaintenanceSource validates a content source, loading its file so that missing files fail at startup.
+// At most one of content, file path and service may be set.
+func newMaintenanceSource(statusCode int, contentType, content, filePath, service string) (maintenanceSource, error) {
+	source := maintenanceSource{statusCode: statusCode, contentType: contentType, content: content}
+
+	sources := 0
+	if content != "" {
+		sources++
+	}
+
+	if filePath != "" {
+		sources++
+		source.file = &maintenanceFile{path: filePath}
+		if _, err := source.file.load(); err != nil {
+			return maintenanceSource{}, fmt.Errorf("failed to load maintenance file: %w", err)
+		}
+	}
+
+	if service != "" {
+		sources++
+		serviceURL, err := url.Parse(service)
+		if err != nil {
+			return maintenanceSource{}, fmt.Errorf("invalid maintenance service URL: %w", err)
+		}
+		if serviceURL.Scheme == "" || serviceURL.Host == "" {
+			return maintenanceSource{}, fmt.Errorf("maintenance service URL must include scheme and host")
+		}
+		source.service = serviceURL
+	}
+
+	if sources > 1 {
+		return maintenanceSource{}, fmt.Errorf("at most one of maintenanceService, maintenanceFilePath or maintenanceContent may be set")
+	}
+
+	return source, nil
+}
+
+// hasSource reports whether a content source is set
+func (s *maintenanceSource) hasSource() bool {
+	return s.content != "" || s.file != nil || s.service != nil
+}
+
+// apply overrides the settings of the source with those set in another source
+func (s *maintenanceSource) apply(other *maintenanceSource) {
+	if other.statusCode != 0 {
+		s.statusCode = other.statusCode
+	}
+	if other.contentType != "" {
+		s.contentType = other.contentType
+	}
+	if other.hasSource() {
+		s.content, s.file, s.service = other.content, other.file, other.service
+	}
+}
+
+// resolveMaintenanceSource combines the top-level maintenance response settings with those of the
+// matched host and then the matched maintenance path group
+func (m *MaintenanceBypass) resolveMaintenanceSource(host *hostMaintenance, group *maintenancePathGroup) maintenanceSource {
+	source := maintenanceSource{statusCode: m.statusCode, contentType: m.contentType}
+	if host != nil {
+		source.apply(&host.maintenanceSource)
+	}
+	if group != nil {
+		source.apply(&group.maintenanceSource)
+	}
+	return source
+}
+
+// serveMaintenanceSource serves the maintenance page from a content source
+func (m *MaintenanceBypass) serveMaintenanceSource(rw http.ResponseWriter, req *http.Request, source *maintenanceSource) {
+	switch {
+	case source.content != "":
+		rw.WriteHeader(source.statusCode)
+		if _, err := rw.Write([]byte(source.content)); err != nil {
+			m.log(LogLevelError, "Error writing maintenance content: %v", err)
+		}
+	case source.file != nil:
+		content, err := source.file.load()
+		if err != nil {
+			m.log(LogLevelError, "Failed to load maintenance file: %v", err)
+			if content == nil {
+				http.Error(rw, "Service Temporarily Unavailable", source.statusCode)
+				return
+			}
+		}
+		rw.WriteHeader(source.statusCode)
+		rw.Write(content)
+	case source.service != nil:
+		m.proxyToService(rw, req, source.service, source.statusCode)
+	}
+}
+
+// maintenanceFile holds a maintenance file, reloading it when the file changes
+type maintenanceFile struct {
+	path    string
+	mutex   sync.Mutex
+	lastMod time.Time
+	content []byte
+}
+
+// load returns the content of the file, reading it again if it was modified since the last load.
+// If the file cannot be read the error is returned. If it was changed to an empty file the previously
+// loaded content is returned along with the error.
+func (f *maintenanceFile) load() ([]byte, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	content, modTime, modified, err := readFileIfModified(f.path, f.lastMod)
+	if err != nil {
+		return nil, fmt.Errorf("error loading maintenance file: %w", err)
+	}
+
+	if !modified {
+		return f.content, nil
+	}
+
+	// An invalid file is only read again once it changes
+	f.lastMod = modTime
+
+	if len(content) == 0 {
+		return f.content, fmt.Errorf("maintenance file is empty: %s", f.path)
+	}
+
+	f.content = content
+
+	return content, nil
+}
+
+// readFileIfModified reads a file if it was modified after lastMod, or if lastMod is zero.
+// It returns the content and modification time of the file and whether it was read.
+func readFileIfModified(path string, lastMod time.Time) ([]byte, time.Time, bool, error) {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return nil, time.Time{}, false, fmt.Errorf("error accessing file: %w", err)
+	}
+
+	// Only reload if file is newer than our last modification time
+	if !lastMod.IsZero() && !fileInfo.ModTime().After(lastMod) {
+		return nil, time.Time{}, false, nil
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, false, fmt.Errorf("error reading file: %w", err)
+	}
+
+	return content, fileInfo.ModTime(), true, nil
+}
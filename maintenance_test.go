@@ -3,6 +3,7 @@ package traefik_maintenance_warden
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -15,6 +16,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
 // testLogWriter is a simple io.Writer that captures logs
@@ -41,6 +44,18 @@ func (m *MockTransportWithError) RoundTrip(req *http.Request) (*http.Response, e
 	return nil, fmt.Errorf("simulated network error")
 }
 
+// MockTransportWithBodyError is a mock transport whose responses fail while the body is read
+type MockTransportWithBodyError struct{}
+
+func (m *MockTransportWithBodyError) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(iotest.ErrReader(fmt.Errorf("simulated body read error"))),
+		Request:    req,
+	}, nil
+}
+
 func TestMaintenanceBypass(t *testing.T) {
 	tests := []struct {
 		name                string
@@ -251,16 +266,14 @@ func TestMaintenanceBypass(t *testing.T) {
 }
 
 func TestJWTTokenBypass(t *testing.T) {
-	// Create a valid JWT token with custom claims
-	// Format: header.payload.signature (we're only concerned with the payload part for this test)
-	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
-	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"1234567890","role":"admin","iat":1516239022}`))
-	signature := base64.RawURLEncoding.EncodeToString([]byte("signature"))
-	validToken := header + "." + payload + "." + signature
+	// Create a valid JWT token with custom claims, signed with the configured secret
+	validToken := signTestJWT(t, "HS256", sha256.New, "s3cret", map[string]interface{}{"sub": "1234567890", "role": "admin", "iat": 1516239022})
 	
 	// Create another valid JWT token with a different claim value
-	wrongPayload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"1234567890","role":"user","iat":1516239022}`))
-	wrongValueToken := header + "." + wrongPayload + "." + signature
+	wrongValueToken := signTestJWT(t, "HS256", sha256.New, "s3cret", map[string]interface{}{"sub": "1234567890", "role": "user", "iat": 1516239022})
+
+	// Create a token with the right claim value but a forged signature
+	forgedToken := signTestJWT(t, "HS256", nil, "", map[string]interface{}{"role": "admin"}) + "forged"
 
 	// Create an invalid JWT token
 	invalidToken := "invalid.token.format"
@@ -292,6 +305,15 @@ func TestJWTTokenBypass(t *testing.T) {
 			tokenToUse:            wrongValueToken,
 			expectedStatusCode:    http.StatusServiceUnavailable,
 		},
+		{
+			name:                  "JWT token with forged signature should not bypass",
+			enabled:               true,
+			bypassJWTTokenHeader:  "Authorization",
+			bypassJWTTokenClaim:   "role",
+			bypassJWTTokenClaimValue: "admin",
+			tokenToUse:            forgedToken,
+			expectedStatusCode:    http.StatusServiceUnavailable,
+		},
 		{
 			name:                  "Invalid JWT token should not bypass",
 			enabled:               true,
@@ -345,6 +367,7 @@ func TestJWTTokenBypass(t *testing.T) {
 				BypassJWTTokenHeader:   tt.bypassJWTTokenHeader,
 				BypassJWTTokenClaim:    tt.bypassJWTTokenClaim,
 				BypassJWTTokenClaimValue: tt.bypassJWTTokenClaimValue,
+				BypassJWTSecret:        "s3cret",
 			}
 
 			// Create the middleware
@@ -385,7 +408,8 @@ func TestJWTTokenBypass(t *testing.T) {
 	}
 }
 
-func TestGetJWTClaimValue(t *testing.T) {
+// TestGetJWTClaimValuesFromToken tests getJWTClaimValues on unsigned test tokens
+func TestGetJWTClaimValuesFromToken(t *testing.T) {
 	// Initialize a test middleware instance
 	middleware := &MaintenanceBypass{
 		logLevel: LogLevelDebug,
@@ -442,12 +466,19 @@ func TestGetJWTClaimValue(t *testing.T) {
 			expected:    "",
 			expectError: true,
 		},
+		{
+			name:        "Undecodable payload",
+			token:       "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.!!.signature",
+			claimName:   "role",
+			expected:    "",
+			expectError: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Get the claim value
-			value, err := middleware.getJWTClaimValue(tt.token, tt.claimName)
+			// Get the claim values
+			values, err := middleware.getJWTClaimValues(tt.token, tt.claimName)
 
 			// Check error
 			if tt.expectError && err == nil {
@@ -457,8 +488,8 @@ func TestGetJWTClaimValue(t *testing.T) {
 			}
 
 			// Check value
-			if value != tt.expected {
-				t.Errorf("Expected claim value %q but got %q", tt.expected, value)
+			if !tt.expectError && (len(values) != 1 || values[0] != tt.expected) {
+				t.Errorf("Expected claim value %q but got %v", tt.expected, values)
 			}
 		})
 	}
@@ -1014,6 +1045,22 @@ func TestCreateConfig(t *testing.T) {
 		t.Errorf("Expected default BypassPaths to be empty, got %v", config.BypassPaths)
 	}
 
+	if config.MaintenanceJSONContent != "" || config.MaintenanceTextContent != "" {
+		t.Errorf("Expected default JSON and text content to be empty, got %q and %q", config.MaintenanceJSONContent, config.MaintenanceTextContent)
+	}
+
+	if len(config.BypassPathRules) != 0 {
+		t.Errorf("Expected default BypassPathRules to be empty, got %v", config.BypassPathRules)
+	}
+
+	if len(config.MaintenancePaths) != 0 {
+		t.Errorf("Expected default MaintenancePaths to be empty, got %v", config.MaintenancePaths)
+	}
+
+	if len(config.Hosts) != 0 {
+		t.Errorf("Expected default Hosts to be empty, got %v", config.Hosts)
+	}
+
 	if !config.BypassFavicon {
 		t.Errorf("Expected default BypassFavicon to be true, got false")
 	}
@@ -1029,9 +1076,73 @@ func TestCreateConfig(t *testing.T) {
 	if config.ContentType != "text/html; charset=utf-8" {
 		t.Errorf("Expected default ContentType to be 'text/html; charset=utf-8', got %q", config.ContentType)
 	}
+
+	if config.RetryAfterFormat != "seconds" {
+		t.Errorf("Expected default RetryAfterFormat to be 'seconds', got %q", config.RetryAfterFormat)
+	}
+
+	if config.DefaultRetryAfter != 3600 {
+		t.Errorf("Expected default DefaultRetryAfter to be 3600, got %d", config.DefaultRetryAfter)
+	}
+
+	if config.EnabledFlagFile != "" {
+		t.Errorf("Expected default EnabledFlagFile to be empty, got %q", config.EnabledFlagFile)
+	}
+
+	if config.FlagFilePollInterval != 5 {
+		t.Errorf("Expected default FlagFilePollInterval to be 5, got %d", config.FlagFilePollInterval)
+	}
+
+	if config.AdminSecret != "" {
+		t.Errorf("Expected default AdminSecret to be empty, got %q", config.AdminSecret)
+	}
+
+	if config.AdminPathPrefix != "/.warden/" {
+		t.Errorf("Expected default AdminPathPrefix to be '/.warden/', got %q", config.AdminPathPrefix)
+	}
+
+	if config.StateURL != "" {
+		t.Errorf("Expected default StateURL to be empty, got %q", config.StateURL)
+	}
+
+	if config.StatePollInterval != 10 {
+		t.Errorf("Expected default StatePollInterval to be 10, got %d", config.StatePollInterval)
+	}
+
+	if config.Mode != "full" {
+		t.Errorf("Expected default Mode to be 'full', got %q", config.Mode)
+	}
+
+	if strings.Join(config.ReadOnlyAllowedMethods, ",") != "GET,HEAD,OPTIONS" {
+		t.Errorf("Expected default ReadOnlyAllowedMethods to be GET, HEAD and OPTIONS, got %v", config.ReadOnlyAllowedMethods)
+	}
+
+	if config.PassThroughPercent != 0 {
+		t.Errorf("Expected default PassThroughPercent to be 0, got %d", config.PassThroughPercent)
+	}
+
+	if config.PassThroughKeySource != "ip" {
+		t.Errorf("Expected default PassThroughKeySource to be 'ip', got %q", config.PassThroughKeySource)
+	}
+
+	if config.BypassCookieName != "warden_bypass" {
+		t.Errorf("Expected default BypassCookieName to be 'warden_bypass', got %q", config.BypassCookieName)
+	}
+
+	if config.BypassTokenHeader != "X-Maintenance-Bypass-Token" {
+		t.Errorf("Expected default BypassTokenHeader to be 'X-Maintenance-Bypass-Token', got %q", config.BypassTokenHeader)
+	}
+
+	if config.BypassJWTJWKSRefreshInterval != 300 {
+		t.Errorf("Expected default BypassJWTJWKSRefreshInterval to be 300, got %d", config.BypassJWTJWKSRefreshInterval)
+	}
+
+	if config.BypassJWTJWKSMinRefetchInterval != 30 {
+		t.Errorf("Expected default BypassJWTJWKSMinRefetchInterval to be 30, got %d", config.BypassJWTJWKSMinRefetchInterval)
+	}
 }
 
-// TestLoadMaintenanceFileErrors tests the error handling in loadMaintenanceFile
+// TestLoadMaintenanceFileErrors tests the error handling in loading the maintenance file
 func TestLoadMaintenanceFileErrors(t *testing.T) {
 	// Create a test handler
 	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
@@ -1169,7 +1280,7 @@ func TestServeMaintenanceFileErrors(t *testing.T) {
 
 	// Now make the file unreadable to simulate failure
 	// We'll replace the file path with a non-existent one
-	m.maintenanceFilePath = "/nonexistent/file.html"
+	m.maintenanceFile = &maintenanceFile{path: "/nonexistent/file.html"}
 
 	// Create a new recorder
 	recorder = httptest.NewRecorder()
@@ -1296,29 +1407,71 @@ func TestLoadMaintenanceFileModificationTime(t *testing.T) {
 	m := middleware.(*MaintenanceBypass)
 
 	// First load should have loaded the file
-	if m.maintenanceFileContent == nil {
+	if m.maintenanceFile.content == nil {
 		t.Fatalf("File content should have been loaded during initialization")
 	}
 
-	if string(m.maintenanceFileContent) != originalContent {
-		t.Errorf("Expected content to be %q, got %q", originalContent, string(m.maintenanceFileContent))
+	if string(m.maintenanceFile.content) != originalContent {
+		t.Errorf("Expected content to be %q, got %q", originalContent, string(m.maintenanceFile.content))
 	}
 
-	initialModTime := m.maintenanceFileLastMod
+	initialModTime := m.maintenanceFile.lastMod
 
-	// Call loadMaintenanceFile again but without changing the file
+	// Load the file again but without changing it
 	// This should not reload the file
-	err = m.loadMaintenanceFile()
+	_, err = m.maintenanceFile.load()
 	if err != nil {
 		t.Fatalf("Error loading maintenance file: %v", err)
 	}
 
 	// The mod time should be the same
-	if !m.maintenanceFileLastMod.Equal(initialModTime) {
+	if !m.maintenanceFile.lastMod.Equal(initialModTime) {
 		t.Errorf("Mod time should not have changed when file wasn't modified")
 	}
 }
 
+// TestMaintenanceFileEmptyUpdate tests that a maintenance file emptied after startup keeps the previous page
+func TestMaintenanceFileEmptyUpdate(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "maintenance.html")
+	if err := ioutil.WriteFile(filePath, []byte("<html><body>Original</body></html>"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	middleware, err := New(context.Background(), nil, &Config{
+		MaintenanceFilePath: filePath,
+		Enabled:             true,
+	}, "maintenance-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	logWriter := &testLogWriter{}
+	m := middleware.(*MaintenanceBypass)
+	m.logger = log.New(logWriter, "", 0)
+	m.logLevel = LogLevelError
+
+	if err := ioutil.WriteFile(filePath, nil, 0644); err != nil {
+		t.Fatalf("Failed to empty test file: %v", err)
+	}
+	modTime := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+		t.Fatalf("Failed to update test file time: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		recorder := httptest.NewRecorder()
+		m.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+
+		if recorder.Body.String() != "<html><body>Original</body></html>" {
+			t.Errorf("Expected the previous page after an empty update, got %q", recorder.Body.String())
+		}
+	}
+
+	if count := strings.Count(logWriter.String(), "maintenance file is empty"); count != 1 {
+		t.Errorf("Expected the empty file to be reported once, got %d reports: %s", count, logWriter.String())
+	}
+}
+
 // TestWriteHeaderOrder tests the ordering of header setting in the custom writer
 func TestWriteHeaderOrder(t *testing.T) {
 	// This tests the edge cases of the custom response writer
@@ -1587,7 +1740,7 @@ func TestServeHTTPDefaultCase(t *testing.T) {
 	bypass := &MaintenanceBypass{
 		next:               nextHandler,
 		maintenanceService: nil, // No service URL
-		maintenanceFilePath: "",  // No file path
+		maintenanceFile:    nil, // No file
 		maintenanceContent: "",   // No content
 		enabled:            true,
 		statusCode:         503,
@@ -1658,8 +1811,8 @@ func (w *MockErrorResponseWriter) WriteHeader(statusCode int) {
 	w.statusCode = statusCode
 }
 
-// TestGetJWTClaimValueComplete tests all claim types in getJWTClaimValue
-func TestGetJWTClaimValueComplete(t *testing.T) {
+// TestGetJWTClaimValuesComplete tests all claim types in getJWTClaimValues
+func TestGetJWTClaimValuesComplete(t *testing.T) {
 	// Create test cases for different claim types
 	testCases := []struct {
 		name        string
@@ -1738,7 +1891,7 @@ func TestGetJWTClaimValueComplete(t *testing.T) {
 			tokenString := "header." + encodedPayload + ".signature"
 
 			// Call the function
-			result, err := bypass.getJWTClaimValue(tokenString, tc.claimName)
+			result, err := bypass.getJWTClaimValues(tokenString, tc.claimName)
 
 			// Verify the result
 			if tc.expectError && err == nil {
@@ -1749,9 +1902,304 @@ func TestGetJWTClaimValueComplete(t *testing.T) {
 				t.Errorf("Expected no error but got: %v", err)
 			}
 
-			if result != tc.expected && !tc.expectError {
-				t.Errorf("Expected claim value %q, got %q", tc.expected, result)
+			if !tc.expectError && (len(result) != 1 || result[0] != tc.expected) {
+				t.Errorf("Expected claim value %q, got %v", tc.expected, result)
 			}
 		})
 	}
 }
+
+// TestRetryAfter tests that Retry-After reflects the expected end of maintenance
+func TestRetryAfter(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	now := time.Date(2025, 1, 5, 2, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name       string
+		config     *Config
+		now        time.Time
+		retryAfter string
+	}{
+		{
+			name: "Default when no end is known",
+			config: &Config{
+				MaintenanceContent: "<html><body>Maintenance</body></html>",
+				Enabled:            true,
+			},
+			now:        now,
+			retryAfter: "3600",
+		},
+		{
+			name: "Configured default when no end is known",
+			config: &Config{
+				MaintenanceContent: "<html><body>Maintenance</body></html>",
+				Enabled:            true,
+				DefaultRetryAfter:  120,
+			},
+			now:        now,
+			retryAfter: "120",
+		},
+		{
+			name: "Remaining seconds until maintenance end time",
+			config: &Config{
+				MaintenanceContent: "<html><body>Maintenance</body></html>",
+				Enabled:            true,
+				MaintenanceEndTime: "2025-01-05T03:00:00Z",
+			},
+			now:        now,
+			retryAfter: "1800",
+		},
+		{
+			name: "Partial seconds are rounded up",
+			config: &Config{
+				MaintenanceContent: "<html><body>Maintenance</body></html>",
+				Enabled:            true,
+				MaintenanceEndTime: "2025-01-05T03:00:00Z",
+			},
+			now:        now.Add(500 * time.Millisecond),
+			retryAfter: "1800",
+		},
+		{
+			name: "Past maintenance end time falls back to default",
+			config: &Config{
+				MaintenanceContent: "<html><body>Maintenance</body></html>",
+				Enabled:            true,
+				MaintenanceEndTime: "2025-01-05T02:00:00Z",
+				DefaultRetryAfter:  60,
+			},
+			now:        now,
+			retryAfter: "60",
+		},
+		{
+			name: "Scheduled window end takes precedence",
+			config: &Config{
+				MaintenanceContent: "<html><body>Maintenance</body></html>",
+				Enabled:            true,
+				MaintenanceEndTime: "2025-01-05T06:00:00Z",
+				Schedule: ScheduleConfig{
+					Windows: []WindowConfig{{Start: "2025-01-05T02:00:00Z", End: "2025-01-05T04:00:00Z"}},
+				},
+			},
+			now:        now,
+			retryAfter: "5400",
+		},
+		{
+			name: "HTTP-date format",
+			config: &Config{
+				MaintenanceContent: "<html><body>Maintenance</body></html>",
+				Enabled:            true,
+				MaintenanceEndTime: "2025-01-05T04:00:00+01:00",
+				RetryAfterFormat:   "http-date",
+			},
+			now:        now,
+			retryAfter: "Sun, 05 Jan 2025 03:00:00 GMT",
+		},
+		{
+			name: "HTTP-date format with default",
+			config: &Config{
+				MaintenanceContent: "<html><body>Maintenance</body></html>",
+				Enabled:            true,
+				RetryAfterFormat:   "http-date",
+			},
+			now:        now,
+			retryAfter: "Sun, 05 Jan 2025 03:30:00 GMT",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			middleware, err := New(context.Background(), nextHandler, tc.config, "retry-after-test")
+			if err != nil {
+				t.Fatalf("Error creating middleware: %v", err)
+			}
+
+			m := middleware.(*MaintenanceBypass)
+			m.now = func() time.Time { return tc.now }
+
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+			m.ServeHTTP(recorder, req)
+
+			if recorder.Code != http.StatusServiceUnavailable {
+				t.Fatalf("Expected status code %d, got %d", http.StatusServiceUnavailable, recorder.Code)
+			}
+
+			if got := recorder.Header().Get("Retry-After"); got != tc.retryAfter {
+				t.Errorf("Expected Retry-After %q, got %q", tc.retryAfter, got)
+			}
+		})
+	}
+}
+
+// TestRetryAfterConfigValidation tests validation of the Retry-After options
+func TestRetryAfterConfigValidation(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	testCases := []struct {
+		name   string
+		config *Config
+	}{
+		{
+			name: "Invalid maintenance end time",
+			config: &Config{
+				MaintenanceContent: "<html><body>Maintenance</body></html>",
+				MaintenanceEndTime: "soon",
+			},
+		},
+		{
+			name: "Invalid Retry-After format",
+			config: &Config{
+				MaintenanceContent: "<html><body>Maintenance</body></html>",
+				RetryAfterFormat:   "minutes",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := New(context.Background(), nextHandler, tc.config, "retry-after-test"); err == nil {
+				t.Errorf("Expected an error but got none")
+			}
+		})
+	}
+}
+
+// TestEnabledFlagFile tests toggling maintenance mode with a flag file
+func TestEnabledFlagFile(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	tmpDir, err := ioutil.TempDir("", "maintenance-flag-file")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	flagFile := filepath.Join(tmpDir, "maintenance.on")
+
+	cfg := &Config{
+		MaintenanceContent:   "<html><body>Maintenance</body></html>",
+		Enabled:              true,
+		EnabledFlagFile:      flagFile,
+		FlagFilePollInterval: 10,
+	}
+
+	middleware, err := New(context.Background(), nextHandler, cfg, "flag-file-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	m := middleware.(*MaintenanceBypass)
+	now := time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	serve := func() int {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+		m.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// Without the flag file, requests pass through
+	if code := serve(); code != http.StatusOK {
+		t.Errorf("Expected status code %d without flag file, got %d", http.StatusOK, code)
+	}
+
+	// Creating the flag file is not noticed until the poll interval has elapsed
+	if err := ioutil.WriteFile(flagFile, nil, 0644); err != nil {
+		t.Fatalf("Failed to create flag file: %v", err)
+	}
+
+	now = now.Add(5 * time.Second)
+	if code := serve(); code != http.StatusOK {
+		t.Errorf("Expected cached status code %d before poll interval, got %d", http.StatusOK, code)
+	}
+
+	now = now.Add(5 * time.Second)
+	if code := serve(); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d with flag file, got %d", http.StatusServiceUnavailable, code)
+	}
+
+	// Removing the flag file turns maintenance off again
+	if err := os.Remove(flagFile); err != nil {
+		t.Fatalf("Failed to remove flag file: %v", err)
+	}
+
+	now = now.Add(10 * time.Second)
+	if code := serve(); code != http.StatusOK {
+		t.Errorf("Expected status code %d after removing flag file, got %d", http.StatusOK, code)
+	}
+
+	// The static configuration still acts as a master switch
+	if err := ioutil.WriteFile(flagFile, nil, 0644); err != nil {
+		t.Fatalf("Failed to create flag file: %v", err)
+	}
+
+	m.enabled = false
+	now = now.Add(10 * time.Second)
+	if code := serve(); code != http.StatusOK {
+		t.Errorf("Expected status code %d when disabled, got %d", http.StatusOK, code)
+	}
+
+	// A flag file path that cannot be checked is logged and treated as absent
+	logWriter := &testLogWriter{}
+	m.logger = log.New(logWriter, "", 0)
+	m.logLevel = LogLevelError
+	m.enabled = true
+	m.enabledFlagFile = filepath.Join(flagFile, "nested")
+
+	now = now.Add(10 * time.Second)
+	if code := serve(); code != http.StatusOK {
+		t.Errorf("Expected status code %d when the flag file cannot be checked, got %d", http.StatusOK, code)
+	}
+
+	if !strings.Contains(logWriter.String(), "Error accessing enabled flag file") {
+		t.Errorf("Expected error log about accessing the flag file, got: %s", logWriter.String())
+	}
+}
+
+// TestEnabledFlagFileWithSchedule tests that the flag file and schedule both activate maintenance
+func TestEnabledFlagFileWithSchedule(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	tmpDir, err := ioutil.TempDir("", "maintenance-flag-file")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	flagFile := filepath.Join(tmpDir, "maintenance.on")
+
+	cfg := &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		Enabled:            true,
+		EnabledFlagFile:    flagFile,
+		Schedule: ScheduleConfig{
+			Windows: []WindowConfig{{Start: "2025-01-05T02:00:00Z", End: "2025-01-05T04:00:00Z"}},
+		},
+	}
+
+	middleware, err := New(context.Background(), nextHandler, cfg, "flag-file-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	m := middleware.(*MaintenanceBypass)
+	m.now = func() time.Time { return time.Date(2025, 1, 5, 3, 0, 0, 0, time.UTC) }
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	m.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d inside scheduled window without flag file, got %d", http.StatusServiceUnavailable, recorder.Code)
+	}
+}
@@ -0,0 +1,167 @@
+package traefik_maintenance_warden
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// mediaTypeHTML is the media type of the HTML maintenance page
+	mediaTypeHTML = "text/html"
+	// mediaTypeJSON is the media type of the JSON maintenance body
+	mediaTypeJSON = "application/json"
+	// mediaTypeText is the media type of the plain-text maintenance body
+	mediaTypeText = "text/plain"
+)
+
+// maintenanceError is the built-in JSON body returned to API clients when no JSON body is configured
+type maintenanceError struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+	Until   string `json:"until,omitempty"`
+}
+
+// acceptRange is a media range of an Accept header with its quality
+type acceptRange struct {
+	mediaType string
+	quality   float64
+}
+
+// parseAccept parses the media ranges of an Accept header, skipping invalid ones
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if value, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = value
+			}
+		}
+
+		ranges = append(ranges, acceptRange{mediaType: mediaType, quality: quality})
+	}
+	return ranges
+}
+
+// mediaRangeSpecificity returns how specifically a media range matches a media type:
+// 2 for an exact match, 1 for type/*, 0 for */* and -1 if it does not match
+func mediaRangeSpecificity(mediaRange string, mediaType string) int {
+	switch {
+	case mediaRange == mediaType:
+		return 2
+	case mediaRange == "*/*":
+		return 0
+	case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*")):
+		return 1
+	default:
+		return -1
+	}
+}
+
+// negotiateMediaType returns the offered media type the client prefers according to its Accept header.
+// The quality of each offer comes from its most specific matching range. Ties go to the offer matched
+// more specifically, then to the earlier offer. Without an acceptable offer the first offer is returned.
+func negotiateMediaType(header string, offers []string) string {
+	ranges := parseAccept(header)
+	best, bestQuality, bestSpecificity := offers[0], 0.0, -1
+
+	for _, offer := range offers {
+		quality, specificity := 0.0, -1
+		for _, r := range ranges {
+			if s := mediaRangeSpecificity(r.mediaType, offer); s > specificity {
+				quality, specificity = r.quality, s
+			}
+		}
+
+		if quality <= 0 {
+			continue
+		}
+
+		if quality > bestQuality || (quality == bestQuality && specificity > bestSpecificity) {
+			best, bestQuality, bestSpecificity = offer, quality, specificity
+		}
+	}
+
+	return best
+}
+
+// alternativeMaintenanceType returns the media type of the body to serve instead of an HTML maintenance
+// page when the client prefers JSON or plain text, or an empty string to serve the page itself.
+// Pages that are not HTML are always served as they are.
+func (m *MaintenanceBypass) alternativeMaintenanceType(rw http.ResponseWriter, req *http.Request, contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || mediaType != mediaTypeHTML {
+		return ""
+	}
+
+	offers := []string{mediaTypeHTML, mediaTypeJSON}
+	if m.maintenanceTextContent != "" {
+		offers = append(offers, mediaTypeText)
+	}
+	if m.problemDetails != nil {
+		offers = append(offers, mediaTypeProblemJSON)
+	}
+
+	// The response depends on the Accept header, so caches must keep the variants apart
+	rw.Header().Add("Vary", "Accept")
+
+	mediaType := negotiateMediaType(req.Header.Get("Accept"), offers)
+	if mediaType == mediaTypeHTML {
+		return ""
+	}
+
+	return mediaType
+}
+
+// serveAlternativeContent serves the JSON, problem details or plain-text maintenance body.
+// Without a configured JSON body, JSON clients get problem details if enabled.
+func (m *MaintenanceBypass) serveAlternativeContent(rw http.ResponseWriter, req *http.Request, mediaType string, statusCode int) {
+	if mediaType == mediaTypeProblemJSON || (mediaType == mediaTypeJSON && m.maintenanceJSONContent == "" && m.problemDetails != nil) {
+		m.serveProblemDetails(rw, req, statusCode, maintenanceMessage)
+		return
+	}
+
+	var body []byte
+
+	switch mediaType {
+	case mediaTypeJSON:
+		rw.Header().Set("Content-Type", "application/json")
+		if m.maintenanceJSONContent != "" {
+			body = []byte(m.maintenanceJSONContent)
+		} else {
+			body = m.builtInMaintenanceJSON()
+		}
+	case mediaTypeText:
+		rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		body = []byte(m.maintenanceTextContent)
+	}
+
+	rw.WriteHeader(statusCode)
+	if _, err := rw.Write(body); err != nil {
+		m.log(LogLevelError, "Error writing maintenance content: %v", err)
+	}
+}
+
+// builtInMaintenanceJSON returns the JSON body used when no JSON body is configured
+func (m *MaintenanceBypass) builtInMaintenanceJSON() []byte {
+	now := m.currentTime()
+
+	body := maintenanceError{
+		Error:   "maintenance",
+		Message: maintenanceMessage,
+	}
+	if end, ok := m.maintenanceEnd(now); ok {
+		body.Until = end.UTC().Format(time.RFC3339)
+	}
+
+	content, _ := json.Marshal(body)
+	return append(content, '\n')
+}
@@ -0,0 +1,154 @@
+package traefik_maintenance_warden
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestNegotiateMediaType tests Accept header negotiation with qualities and wildcards
+func TestNegotiateMediaType(t *testing.T) {
+	offers := []string{mediaTypeHTML, mediaTypeJSON, mediaTypeText}
+
+	testCases := []struct {
+		name     string
+		accept   string
+		expected string
+	}{
+		{"No Accept header", "", mediaTypeHTML},
+		{"Any type", "*/*", mediaTypeHTML},
+		{"Browser", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", mediaTypeHTML},
+		{"JSON", "application/json", mediaTypeJSON},
+		{"JSON before wildcard", "application/json, text/plain, */*", mediaTypeJSON},
+		{"Plain text", "text/plain", mediaTypeText},
+		{"Quality preference", "text/html;q=0.5, application/json;q=0.8", mediaTypeJSON},
+		{"Type wildcard", "text/*;q=0.9, application/json;q=0.1", mediaTypeHTML},
+		{"Rejected type", "text/html;q=0, */*", mediaTypeJSON},
+		{"Nothing acceptable", "image/png", mediaTypeHTML},
+		{"Invalid range skipped", "invalid;;, application/json", mediaTypeJSON},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if mediaType := negotiateMediaType(tc.accept, offers); mediaType != tc.expected {
+				t.Errorf("Expected %s for Accept %q, got %s", tc.expected, tc.accept, mediaType)
+			}
+		})
+	}
+}
+
+// TestContentNegotiation tests that clients get HTML, JSON or plain-text maintenance responses
+func TestContentNegotiation(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	html := "<html><body>Maintenance</body></html>"
+
+	testCases := []struct {
+		name                string
+		config              Config
+		accept              string
+		expectedContentType string
+		expectedBody        string
+	}{
+		{"HTML by default", Config{MaintenanceContent: html}, "", "text/html; charset=utf-8", html},
+		{"Configured JSON", Config{MaintenanceContent: html, MaintenanceJSONContent: `{"status":"maintenance"}`}, "application/json", "application/json", `{"status":"maintenance"}`},
+		{"Configured text", Config{MaintenanceContent: html, MaintenanceTextContent: "Down for maintenance"}, "text/plain", "text/plain; charset=utf-8", "Down for maintenance"},
+		{"Text falls back to HTML when not configured", Config{MaintenanceContent: html}, "text/plain", "text/html; charset=utf-8", html},
+		{"Non-HTML page is not negotiated", Config{MaintenanceContent: "Plain page", ContentType: "text/plain"}, "application/json", "text/plain", "Plain page"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := tc.config
+			cfg.Enabled = true
+
+			middleware, err := New(context.Background(), nextHandler, &cfg, "negotiation-test")
+			if err != nil {
+				t.Fatalf("Error creating middleware: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			recorder := httptest.NewRecorder()
+			middleware.ServeHTTP(recorder, req)
+
+			if recorder.Code != http.StatusServiceUnavailable {
+				t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, recorder.Code)
+			}
+
+			if contentType := recorder.Header().Get("Content-Type"); contentType != tc.expectedContentType {
+				t.Errorf("Expected content type %q, got %q", tc.expectedContentType, contentType)
+			}
+
+			if body := recorder.Body.String(); body != tc.expectedBody {
+				t.Errorf("Expected body %q, got %q", tc.expectedBody, body)
+			}
+		})
+	}
+}
+
+// TestBuiltInMaintenanceJSON tests the JSON body used when only HTML is configured
+func TestBuiltInMaintenanceJSON(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		Enabled:            true,
+		MaintenanceEndTime: "2025-01-05T04:00:00Z",
+	}, "negotiation-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	m := middleware.(*MaintenanceBypass)
+	m.now = func() time.Time { return time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC) }
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/api/orders", nil)
+	req.Header.Set("Accept", "application/json")
+	recorder := httptest.NewRecorder()
+	m.ServeHTTP(recorder, req)
+
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("Expected JSON content type, got %q", contentType)
+	}
+
+	if vary := recorder.Header().Get("Vary"); !strings.Contains(vary, "Accept") {
+		t.Errorf("Expected Vary to include Accept, got %q", vary)
+	}
+
+	var body maintenanceError
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Error decoding JSON body: %v", err)
+	}
+
+	if body.Error != "maintenance" || body.Message == "" || body.Until != "2025-01-05T04:00:00Z" {
+		t.Errorf("Unexpected JSON body: %+v", body)
+	}
+}
+
+// TestAlternativeContentWriteError tests that errors writing the JSON or plain-text body are logged
+func TestAlternativeContentWriteError(t *testing.T) {
+	logWriter := &testLogWriter{}
+	m := &MaintenanceBypass{
+		maintenanceTextContent: "Down for maintenance",
+		logger:                 log.New(logWriter, "", 0),
+		logLevel:               LogLevelError,
+	}
+
+	m.serveAlternativeContent(&MockErrorResponseWriter{}, httptest.NewRequest(http.MethodGet, "http://example.com/", nil), mediaTypeText, http.StatusServiceUnavailable)
+
+	if !strings.Contains(logWriter.String(), "Error writing maintenance content") {
+		t.Errorf("Expected error log about writing maintenance content, got: %s", logWriter.String())
+	}
+}
@@ -0,0 +1,120 @@
+package traefik_maintenance_warden
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	// pathMatchExact matches only the exact path
+	pathMatchExact = "exact"
+	// pathMatchPrefix matches any path starting with the pattern
+	pathMatchPrefix = "prefix"
+	// pathMatchSegmentPrefix matches the path and everything below it, but not siblings sharing its prefix
+	pathMatchSegmentPrefix = "segment-prefix"
+	// pathMatchGlob matches shell-style patterns where * does not cross a slash
+	pathMatchGlob = "glob"
+	// pathMatchRegex matches a regular expression against the whole path
+	pathMatchRegex = "regex"
+)
+
+// PathRuleConfig configures a request path rule
+type PathRuleConfig struct {
+	// Match is how the path is matched ("exact", "prefix", "segment-prefix", "glob" or "regex")
+	Match string `json:"match,omitempty"`
+
+	// Path is the path or pattern to match
+	Path string `json:"path,omitempty"`
+}
+
+// pathRule is a compiled request path rule
+type pathRule struct {
+	match   string
+	pattern string
+	regex   *regexp.Regexp
+}
+
+// newPathRule validates a path rule and compiles its pattern
+func newPathRule(config PathRuleConfig) (pathRule, error) {
+	if config.Path == "" {
+		return pathRule{}, fmt.Errorf("path must not be empty")
+	}
+
+	rule := pathRule{match: config.Match, pattern: config.Path}
+
+	switch config.Match {
+	case pathMatchExact, pathMatchPrefix, pathMatchSegmentPrefix:
+		if !strings.HasPrefix(config.Path, "/") {
+			return pathRule{}, fmt.Errorf("path %q must start with /", config.Path)
+		}
+	case pathMatchGlob:
+		if !strings.HasPrefix(config.Path, "/") {
+			return pathRule{}, fmt.Errorf("path %q must start with /", config.Path)
+		}
+		if _, err := path.Match(config.Path, ""); err != nil {
+			return pathRule{}, fmt.Errorf("invalid glob %q: %w", config.Path, err)
+		}
+	case pathMatchRegex:
+		// Anchor the expression so it has to match the whole path
+		regex, err := regexp.Compile("^(?:" + config.Path + ")$")
+		if err != nil {
+			return pathRule{}, fmt.Errorf("invalid regex %q: %w", config.Path, err)
+		}
+		rule.regex = regex
+	default:
+		return pathRule{}, fmt.Errorf("match must be one of %q, %q, %q, %q or %q, got %q",
+			pathMatchExact, pathMatchPrefix, pathMatchSegmentPrefix, pathMatchGlob, pathMatchRegex, config.Match)
+	}
+
+	return rule, nil
+}
+
+// newPathRules validates and compiles a list of path rules
+func newPathRules(configs []PathRuleConfig) ([]pathRule, error) {
+	rules := make([]pathRule, 0, len(configs))
+	for i, config := range configs {
+		rule, err := newPathRule(config)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// matches reports whether a request path matches the rule
+func (r pathRule) matches(requestPath string) bool {
+	switch r.match {
+	case pathMatchExact:
+		return requestPath == r.pattern
+	case pathMatchPrefix:
+		return strings.HasPrefix(requestPath, r.pattern)
+	case pathMatchSegmentPrefix:
+		prefix := strings.TrimSuffix(r.pattern, "/")
+		return requestPath == prefix || strings.HasPrefix(requestPath, prefix+"/")
+	case pathMatchGlob:
+		matched, _ := path.Match(r.pattern, requestPath)
+		return matched
+	case pathMatchRegex:
+		return r.regex.MatchString(requestPath)
+	default:
+		return false
+	}
+}
+
+// String describes the rule for logging
+func (r pathRule) String() string {
+	return r.match + " " + r.pattern
+}
+
+// matchPathRule returns the first rule matching a request path
+func matchPathRule(rules []pathRule, requestPath string) (pathRule, bool) {
+	for _, rule := range rules {
+		if rule.matches(requestPath) {
+			return rule, true
+		}
+	}
+	return pathRule{}, false
+}
@@ -0,0 +1,136 @@
+package traefik_maintenance_warden
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestPathRuleMatches tests each path match type
+func TestPathRuleMatches(t *testing.T) {
+	testCases := []struct {
+		name     string
+		config   PathRuleConfig
+		path     string
+		expected bool
+	}{
+		{"Exact match", PathRuleConfig{Match: "exact", Path: "/health"}, "/health", true},
+		{"Exact no match below", PathRuleConfig{Match: "exact", Path: "/health"}, "/health/live", false},
+		{"Prefix match", PathRuleConfig{Match: "prefix", Path: "/api"}, "/apiary", true},
+		{"Prefix no match", PathRuleConfig{Match: "prefix", Path: "/api"}, "/ap", false},
+		{"Segment prefix same path", PathRuleConfig{Match: "segment-prefix", Path: "/api"}, "/api", true},
+		{"Segment prefix below", PathRuleConfig{Match: "segment-prefix", Path: "/api"}, "/api/users", true},
+		{"Segment prefix sibling", PathRuleConfig{Match: "segment-prefix", Path: "/api"}, "/apiary", false},
+		{"Segment prefix trailing slash", PathRuleConfig{Match: "segment-prefix", Path: "/api/"}, "/api", true},
+		{"Glob match", PathRuleConfig{Match: "glob", Path: "/assets/*.css"}, "/assets/site.css", true},
+		{"Glob does not cross slash", PathRuleConfig{Match: "glob", Path: "/assets/*.css"}, "/assets/css/site.css", false},
+		{"Glob wrong extension", PathRuleConfig{Match: "glob", Path: "/assets/*.css"}, "/assets/site.js", false},
+		{"Regex match", PathRuleConfig{Match: "regex", Path: "/v[0-9]+/health"}, "/v12/health", true},
+		{"Regex anchored at end", PathRuleConfig{Match: "regex", Path: "/v[0-9]+/health"}, "/v1/healthz", false},
+		{"Regex anchored at start", PathRuleConfig{Match: "regex", Path: "/v[0-9]+/health"}, "/x/v1/health", false},
+		{"Regex alternation anchored", PathRuleConfig{Match: "regex", Path: "/a|/b"}, "/a/c", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := newPathRule(tc.config)
+			if err != nil {
+				t.Fatalf("Error creating path rule: %v", err)
+			}
+
+			if matched := rule.matches(tc.path); matched != tc.expected {
+				t.Errorf("Expected %s to match %s: %t, got %t", rule, tc.path, tc.expected, matched)
+			}
+		})
+	}
+
+	// A rule without a valid match type never matches
+	if (pathRule{pattern: "/"}).matches("/") {
+		t.Errorf("Expected a rule without a match type not to match")
+	}
+}
+
+// TestPathRuleString tests the rule description used in log messages
+func TestPathRuleString(t *testing.T) {
+	rule, err := newPathRule(PathRuleConfig{Match: "segment-prefix", Path: "/api"})
+	if err != nil {
+		t.Fatalf("Error creating path rule: %v", err)
+	}
+
+	if description := rule.String(); description != "segment-prefix /api" {
+		t.Errorf("Expected description %q, got %q", "segment-prefix /api", description)
+	}
+}
+
+// TestPathRuleValidation tests that invalid path rules fail at config time
+func TestPathRuleValidation(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config PathRuleConfig
+	}{
+		{"Missing match", PathRuleConfig{Path: "/api"}},
+		{"Unknown match", PathRuleConfig{Match: "suffix", Path: "/api"}},
+		{"Empty path", PathRuleConfig{Match: "exact"}},
+		{"Relative path", PathRuleConfig{Match: "prefix", Path: "api"}},
+		{"Relative glob", PathRuleConfig{Match: "glob", Path: "assets/*.css"}},
+		{"Invalid glob", PathRuleConfig{Match: "glob", Path: "/assets/[a-"}},
+		{"Invalid regex", PathRuleConfig{Match: "regex", Path: "/v[0-9+/health"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(context.Background(), nil, &Config{
+				MaintenanceContent: "<html><body>Maintenance</body></html>",
+				BypassPathRules:    []PathRuleConfig{tc.config},
+			}, "path-rules-test")
+			if err == nil {
+				t.Errorf("Expected an error but got none")
+			}
+		})
+	}
+}
+
+// TestBypassPathRules tests that requests matching a bypass path rule pass through
+func TestBypassPathRules(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		Enabled:            true,
+		BypassPathRules: []PathRuleConfig{
+			{Match: "segment-prefix", Path: "/api"},
+			{Match: "glob", Path: "/assets/*.css"},
+			{Match: "regex", Path: "/v[0-9]+/health"},
+		},
+	}, "path-rules-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	testCases := []struct {
+		path         string
+		expectedCode int
+	}{
+		{"/api", http.StatusOK},
+		{"/api/users", http.StatusOK},
+		{"/apiary", http.StatusServiceUnavailable},
+		{"/assets/site.css", http.StatusOK},
+		{"/assets/site.js", http.StatusServiceUnavailable},
+		{"/v2/health", http.StatusOK},
+		{"/v2/status", http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			middleware.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://example.com"+tc.path, nil))
+
+			if recorder.Code != tc.expectedCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedCode, recorder.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package traefik_maintenance_warden
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// mediaTypeProblemJSON is the media type of RFC 9457 problem details
+	mediaTypeProblemJSON = "application/problem+json"
+	// maintenanceMessage is the explanation of the maintenance used when none is configured
+	maintenanceMessage = "The service is temporarily unavailable for maintenance. Please try again later."
+)
+
+// ProblemDetailsConfig configures RFC 9457 problem details maintenance responses
+type ProblemDetailsConfig struct {
+	// Enabled turns on problem details responses
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Type is a URI reference identifying the problem type (default: about:blank)
+	Type string `json:"type,omitempty"`
+
+	// Title is a short summary of the problem type (default: the reason phrase of the status code)
+	Title string `json:"title,omitempty"`
+
+	// Detail is an explanation of this occurrence of the problem (default: a generic maintenance or read-only message)
+	Detail string `json:"detail,omitempty"`
+}
+
+// problemDetails is an RFC 9457 problem details object with the maintenance end and retry hint as extension members
+type problemDetails struct {
+	Type       string `json:"type"`
+	Title      string `json:"title"`
+	Status     int    `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+	Until      string `json:"until,omitempty"`
+	RetryAfter int64  `json:"retryAfter"`
+}
+
+// problemDetailsTemplate holds the configured members of the problem details responses
+type problemDetailsTemplate struct {
+	typeURI string
+	title   string
+	detail  string
+}
+
+// newProblemDetailsTemplate validates the problem details configuration, returning nil if it is disabled
+func newProblemDetailsTemplate(config ProblemDetailsConfig) (*problemDetailsTemplate, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+
+	typeURI := config.Type
+	if typeURI == "" {
+		typeURI = "about:blank"
+	}
+	if _, err := url.Parse(typeURI); err != nil {
+		return nil, fmt.Errorf("invalid type URI: %w", err)
+	}
+
+	return &problemDetailsTemplate{typeURI: typeURI, title: config.Title, detail: config.Detail}, nil
+}
+
+// serveProblemDetails serves an RFC 9457 problem details maintenance response.
+// The configured detail takes precedence over the given one.
+func (m *MaintenanceBypass) serveProblemDetails(rw http.ResponseWriter, req *http.Request, statusCode int, detail string) {
+	now := m.currentTime()
+
+	problem := problemDetails{
+		Type:       m.problemDetails.typeURI,
+		Title:      m.problemDetails.title,
+		Status:     statusCode,
+		Detail:     m.problemDetails.detail,
+		RetryAfter: m.retryAfterSeconds(now),
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(statusCode)
+	}
+	if problem.Detail == "" {
+		problem.Detail = detail
+	}
+	if end, ok := m.maintenanceEnd(now); ok {
+		problem.Until = end.UTC().Format(time.RFC3339)
+	}
+
+	rw.Header().Set("Content-Type", mediaTypeProblemJSON)
+	rw.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(rw).Encode(problem); err != nil {
+		m.log(LogLevelError, "Error writing problem details: %v", err)
+	}
+}
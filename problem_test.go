@@ -0,0 +1,244 @@
+package traefik_maintenance_warden
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestProblemDetails tests RFC 9457 problem details maintenance responses
+func TestProblemDetails(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	html := "<html><body>Maintenance</body></html>"
+
+	testCases := []struct {
+		name                string
+		config              Config
+		accept              string
+		expectedContentType string
+		expectedProblem     *problemDetails
+	}{
+		{
+			name: "Problem details without other content",
+			config: Config{
+				MaintenanceEndTime: "2025-01-05T04:00:00Z",
+				ProblemDetails: ProblemDetailsConfig{
+					Enabled: true,
+					Type:    "https://status.example.com/problems/maintenance",
+					Title:   "Scheduled maintenance",
+					Detail:  "The orders API is being upgraded.",
+				},
+			},
+			expectedContentType: "application/problem+json",
+			expectedProblem: &problemDetails{
+				Type:       "https://status.example.com/problems/maintenance",
+				Title:      "Scheduled maintenance",
+				Status:     http.StatusServiceUnavailable,
+				Detail:     "The orders API is being upgraded.",
+				Until:      "2025-01-05T04:00:00Z",
+				RetryAfter: 7200,
+			},
+		},
+		{
+			name:                "Defaults",
+			config:              Config{StatusCode: http.StatusBadGateway, ProblemDetails: ProblemDetailsConfig{Enabled: true}},
+			accept:              "application/problem+json",
+			expectedContentType: "application/problem+json",
+			expectedProblem: &problemDetails{
+				Type:       "about:blank",
+				Title:      "Bad Gateway",
+				Status:     http.StatusBadGateway,
+				Detail:     "The service is temporarily unavailable for maintenance. Please try again later.",
+				RetryAfter: 3600,
+			},
+		},
+		{
+			name:                "Browsers still get the HTML page",
+			config:              Config{MaintenanceContent: html, ProblemDetails: ProblemDetailsConfig{Enabled: true}},
+			accept:              "text/html",
+			expectedContentType: "text/html; charset=utf-8",
+		},
+		{
+			name:                "JSON clients get problem details instead of the built-in body",
+			config:              Config{MaintenanceContent: html, ProblemDetails: ProblemDetailsConfig{Enabled: true}},
+			accept:              "application/json",
+			expectedContentType: "application/problem+json",
+			expectedProblem: &problemDetails{
+				Type:       "about:blank",
+				Title:      "Service Unavailable",
+				Status:     http.StatusServiceUnavailable,
+				Detail:     "The service is temporarily unavailable for maintenance. Please try again later.",
+				RetryAfter: 3600,
+			},
+		},
+		{
+			name:                "Configured JSON body takes precedence for JSON clients",
+			config:              Config{MaintenanceContent: html, MaintenanceJSONContent: `{"status":"maintenance"}`, ProblemDetails: ProblemDetailsConfig{Enabled: true}},
+			accept:              "application/json",
+			expectedContentType: "application/json",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := tc.config
+			cfg.Enabled = true
+
+			middleware, err := New(context.Background(), nextHandler, &cfg, "problem-test")
+			if err != nil {
+				t.Fatalf("Error creating middleware: %v", err)
+			}
+
+			m := middleware.(*MaintenanceBypass)
+			m.now = func() time.Time { return time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC) }
+
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/api/orders", nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			recorder := httptest.NewRecorder()
+			m.ServeHTTP(recorder, req)
+
+			if contentType := recorder.Header().Get("Content-Type"); contentType != tc.expectedContentType {
+				t.Errorf("Expected content type %q, got %q", tc.expectedContentType, contentType)
+			}
+
+			if tc.expectedProblem == nil {
+				return
+			}
+
+			if recorder.Code != tc.expectedProblem.Status {
+				t.Errorf("Expected status code %d, got %d", tc.expectedProblem.Status, recorder.Code)
+			}
+
+			var problem problemDetails
+			if err := json.Unmarshal(recorder.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("Error decoding problem details: %v", err)
+			}
+
+			if problem != *tc.expectedProblem {
+				t.Errorf("Expected problem details %+v, got %+v", *tc.expectedProblem, problem)
+			}
+		})
+	}
+}
+
+// TestProblemDetailsStatusCode tests that problem details follow the status code of a maintenance path group
+func TestProblemDetailsStatusCode(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		Enabled:        true,
+		ProblemDetails: ProblemDetailsConfig{Enabled: true},
+		MaintenancePaths: []MaintenancePathConfig{{
+			Paths:      []PathRuleConfig{{Match: "segment-prefix", Path: "/api/v2/payments"}},
+			StatusCode: http.StatusBadGateway,
+		}},
+	}, "problem-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	middleware.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "http://example.com/api/v2/payments", nil))
+
+	var problem problemDetails
+	if err := json.Unmarshal(recorder.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Error decoding problem details: %v", err)
+	}
+
+	if recorder.Code != http.StatusBadGateway || problem.Status != http.StatusBadGateway || problem.Title != "Bad Gateway" {
+		t.Errorf("Expected status %d in response and problem details, got %d and %+v", http.StatusBadGateway, recorder.Code, problem)
+	}
+}
+
+// TestProblemDetailsReadOnly tests that blocked writes in read-only mode get problem details when enabled
+func TestProblemDetailsReadOnly(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	testCases := []struct {
+		name           string
+		detail         string
+		expectedDetail string
+	}{
+		{"Read-only detail by default", "", "The service is in read-only maintenance mode; POST requests are temporarily unavailable."},
+		{"Configured detail", "The orders API is being upgraded.", "The orders API is being upgraded."},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			middleware, err := New(context.Background(), nextHandler, &Config{
+				MaintenanceContent: "<html><body>Maintenance</body></html>",
+				Enabled:            true,
+				Mode:               "readonly",
+				ProblemDetails:     ProblemDetailsConfig{Enabled: true, Detail: tc.detail},
+			}, "problem-test")
+			if err != nil {
+				t.Fatalf("Error creating middleware: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "http://example.com/api/orders", nil)
+			req.Header.Set("Accept", "application/problem+json")
+			recorder := httptest.NewRecorder()
+			middleware.ServeHTTP(recorder, req)
+
+			if contentType := recorder.Header().Get("Content-Type"); contentType != "application/problem+json" {
+				t.Errorf("Expected problem details content type, got %q", contentType)
+			}
+
+			var problem problemDetails
+			if err := json.Unmarshal(recorder.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("Error decoding problem details: %v", err)
+			}
+
+			if recorder.Code != http.StatusServiceUnavailable || problem.Status != http.StatusServiceUnavailable || problem.Detail != tc.expectedDetail {
+				t.Errorf("Unexpected response %d with problem details %+v", recorder.Code, problem)
+			}
+		})
+	}
+}
+
+// TestProblemDetailsWriteError tests that errors writing problem details are logged
+func TestProblemDetailsWriteError(t *testing.T) {
+	logWriter := &testLogWriter{}
+	m := &MaintenanceBypass{
+		problemDetails: &problemDetailsTemplate{typeURI: "about:blank"},
+		logger:         log.New(logWriter, "", 0),
+		logLevel:       LogLevelError,
+	}
+
+	m.serveProblemDetails(&MockErrorResponseWriter{}, httptest.NewRequest(http.MethodGet, "http://example.com/", nil), http.StatusServiceUnavailable, maintenanceMessage)
+
+	if !strings.Contains(logWriter.String(), "Error writing problem details") {
+		t.Errorf("Expected error log about writing problem details, got: %s", logWriter.String())
+	}
+}
+
+// TestProblemDetailsValidation tests validation of the problem details configuration
+func TestProblemDetailsValidation(t *testing.T) {
+	_, err := New(context.Background(), nil, &Config{
+		ProblemDetails: ProblemDetailsConfig{Enabled: true, Type: "http://[invalid"},
+	}, "problem-test")
+	if err == nil {
+		t.Errorf("Expected an error for an invalid type URI")
+	}
+
+	_, err = New(context.Background(), nil, &Config{
+		ProblemDetails: ProblemDetailsConfig{Type: "https://status.example.com/problems/maintenance"},
+	}, "problem-test")
+	if err == nil {
+		t.Errorf("Expected disabled problem details not to count as maintenance content")
+	}
+}
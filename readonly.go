@@ -0,0 +1,84 @@
+package traefik_maintenance_warden
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// modeFull blocks all requests that do not meet a bypass condition
+	modeFull = "full"
+	// modeReadOnly lets safe methods through and blocks writes
+	modeReadOnly = "readonly"
+)
+
+// defaultReadOnlyAllowedMethods are the methods passed through in read-only mode by default
+var defaultReadOnlyAllowedMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+
+// readOnlyError is the JSON body returned to API clients for blocked writes in read-only mode
+type readOnlyError struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// newReadOnlyMethods validates the maintenance mode and builds the set of methods allowed in read-only mode
+func newReadOnlyMethods(mode string, methods []string) (map[string]bool, error) {
+	switch mode {
+	case "", modeFull:
+		return nil, nil
+	case modeReadOnly:
+	default:
+		return nil, fmt.Errorf("mode must be %q or %q", modeFull, modeReadOnly)
+	}
+
+	if len(methods) == 0 {
+		methods = defaultReadOnlyAllowedMethods
+	}
+
+	allowed := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		allowed[strings.ToUpper(strings.TrimSpace(method))] = true
+	}
+
+	return allowed, nil
+}
+
+// isAPIRequest reports whether the client prefers a JSON response over HTML, using the same
+// Accept negotiation as the maintenance page so ranges the client refused with q=0 are ignored
+func isAPIRequest(req *http.Request) bool {
+	header := req.Header.Get("Accept")
+
+	// Vendor JSON types such as application/vnd.api+json count as JSON too
+	offers := []string{mediaTypeHTML, mediaTypeJSON, mediaTypeProblemJSON}
+	for _, r := range parseAccept(header) {
+		if strings.HasSuffix(r.mediaType, "+json") && r.mediaType != mediaTypeProblemJSON {
+			offers = append(offers, r.mediaType)
+		}
+	}
+
+	return negotiateMediaType(header, offers) != mediaTypeHTML
+}
+
+// serveReadOnlyError writes the JSON error returned to API clients for blocked writes,
+// as problem details if those are enabled
+func (m *MaintenanceBypass) serveReadOnlyError(rw http.ResponseWriter, req *http.Request, statusCode int) {
+	message := fmt.Sprintf("The service is in read-only maintenance mode; %s requests are temporarily unavailable.", req.Method)
+
+	if m.problemDetails != nil {
+		m.serveProblemDetails(rw, req, statusCode, message)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(statusCode)
+
+	err := json.NewEncoder(rw).Encode(readOnlyError{
+		Error:   "read_only",
+		Message: message,
+	})
+	if err != nil {
+		m.log(LogLevelError, "Error writing read-only error: %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package traefik_maintenance_warden
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestReadOnlyMode tests that read-only mode only blocks write requests
+func TestReadOnlyMode(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	testCases := []struct {
+		name           string
+		allowedMethods []string
+		method         string
+		accept         string
+		bypassHeader   bool
+		expectedStatus int
+		expectJSON     bool
+	}{
+		{"GET passes through", nil, http.MethodGet, "", false, http.StatusOK, false},
+		{"HEAD passes through", nil, http.MethodHead, "", false, http.StatusOK, false},
+		{"OPTIONS passes through", nil, http.MethodOptions, "", false, http.StatusOK, false},
+		{"POST is blocked", nil, http.MethodPost, "text/html", false, http.StatusServiceUnavailable, false},
+		{"PUT is blocked", nil, http.MethodPut, "", false, http.StatusServiceUnavailable, false},
+		{"PATCH is blocked", nil, http.MethodPatch, "", false, http.StatusServiceUnavailable, false},
+		{"DELETE from API client gets JSON", nil, http.MethodDelete, "application/json", false, http.StatusServiceUnavailable, true},
+		{"POST from problem+json client gets JSON", nil, http.MethodPost, "application/problem+json;q=0.9", false, http.StatusServiceUnavailable, true},
+		{"POST from vendor JSON client gets JSON", nil, http.MethodPost, "application/vnd.api+json", false, http.StatusServiceUnavailable, true},
+		{"POST refusing JSON gets the page", nil, http.MethodPost, "text/html, application/json;q=0", false, http.StatusServiceUnavailable, false},
+		{"POST preferring HTML gets the page", nil, http.MethodPost, "text/html, application/json;q=0.1", false, http.StatusServiceUnavailable, false},
+		{"POST from wildcard client gets the page", nil, http.MethodPost, "*/*", false, http.StatusServiceUnavailable, false},
+		{"POST with bypass header passes through", nil, http.MethodPost, "", true, http.StatusOK, false},
+		{"Custom allowed methods", []string{"get", "POST"}, http.MethodPost, "", false, http.StatusOK, false},
+		{"HEAD not in custom allowed methods", []string{"GET"}, http.MethodHead, "", false, http.StatusServiceUnavailable, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &Config{
+				MaintenanceContent:     "<html><body>Maintenance</body></html>",
+				Enabled:                true,
+				Mode:                   "readonly",
+				ReadOnlyAllowedMethods: tc.allowedMethods,
+				BypassHeader:           "X-Maintenance-Bypass",
+				BypassHeaderValue:      "true",
+			}
+
+			middleware, err := New(context.Background(), nextHandler, cfg, "readonly-test")
+			if err != nil {
+				t.Fatalf("Error creating middleware: %v", err)
+			}
+
+			req := httptest.NewRequest(tc.method, "http://example.com/orders", nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			if tc.bypassHeader {
+				req.Header.Set("X-Maintenance-Bypass", "true")
+			}
+
+			recorder := httptest.NewRecorder()
+			middleware.ServeHTTP(recorder, req)
+
+			if recorder.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedStatus, recorder.Code)
+			}
+
+			if tc.expectedStatus == http.StatusOK {
+				return
+			}
+
+			if tc.expectJSON {
+				if contentType := recorder.Header().Get("Content-Type"); contentType != "application/json" {
+					t.Errorf("Expected JSON content type, got %q", contentType)
+				}
+
+				var body readOnlyError
+				if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+					t.Fatalf("Error decoding JSON body: %v", err)
+				}
+
+				if body.Error != "read_only" || !strings.Contains(body.Message, tc.method) {
+					t.Errorf("Unexpected JSON body: %+v", body)
+				}
+			} else if recorder.Body.String() != "<html><body>Maintenance</body></html>" {
+				t.Errorf("Expected maintenance page, got %q", recorder.Body.String())
+			}
+		})
+	}
+}
+
+// TestFullModeBlocksSafeMethods tests that the default mode still blocks reads
+func TestFullModeBlocksSafeMethods(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		Enabled:            true,
+		Mode:               "full",
+	}, "readonly-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	req.Header.Set("Accept", "application/json")
+	recorder := httptest.NewRecorder()
+	middleware.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, recorder.Code)
+	}
+
+	// API clients get the maintenance JSON body rather than the read-only error
+	var body maintenanceError
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Error decoding JSON body: %v", err)
+	}
+
+	if body.Error != "maintenance" {
+		t.Errorf("Expected maintenance JSON body, got %q", recorder.Body.String())
+	}
+}
+
+// TestReadOnlyErrorWriteError tests that errors writing the read-only JSON error are logged
+func TestReadOnlyErrorWriteError(t *testing.T) {
+	logWriter := &testLogWriter{}
+	m := &MaintenanceBypass{
+		logger:   log.New(logWriter, "", 0),
+		logLevel: LogLevelError,
+	}
+
+	m.serveReadOnlyError(&MockErrorResponseWriter{}, httptest.NewRequest(http.MethodPost, "http://example.com/orders", nil), http.StatusServiceUnavailable)
+
+	if !strings.Contains(logWriter.String(), "Error writing read-only error") {
+		t.Errorf("Expected error log about writing the read-only error, got: %s", logWriter.String())
+	}
+}
+
+// TestInvalidMode tests validation of the maintenance mode
+func TestInvalidMode(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	_, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		Mode:               "partial",
+	}, "readonly-test")
+	if err == nil {
+		t.Errorf("Expected an error for an invalid mode")
+	}
+}
@@ -0,0 +1,106 @@
+package traefik_maintenance_warden
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxRemoteStateSize limits the size of the remote state document
+const maxRemoteStateSize = 64 * 1024
+
+// remoteState is the JSON document served by the state URL
+type remoteState struct {
+	// Enabled controls whether maintenance mode is active
+	Enabled *bool `json:"enabled"`
+
+	// Until is the RFC3339 time at which maintenance is expected to end
+	Until string `json:"until,omitempty"`
+}
+
+// watchRemoteState polls the state URL until the context is cancelled
+func (m *MaintenanceBypass) watchRemoteState(ctx context.Context) {
+	ticker := time.NewTicker(m.statePollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.pollRemoteState(ctx); err != nil {
+			m.log(LogLevelError, "Failed to poll remote state, keeping current state: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			m.log(LogLevelDebug, "Stopping remote state polling")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollRemoteState fetches the remote state document once and applies it.
+// The ETag of the last document is sent so unchanged state is not transferred again.
+func (m *MaintenanceBypass) pollRemoteState(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.stateURL, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if m.stateETag != "" {
+		req.Header.Set("If-None-Match", m.stateETag)
+	}
+
+	resp, err := m.stateClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error fetching remote state: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		m.log(LogLevelDebug, "Remote state not modified")
+		return nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d from remote state", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteStateSize))
+	if err != nil {
+		return fmt.Errorf("error reading remote state: %w", err)
+	}
+
+	var state remoteState
+	if err := json.Unmarshal(body, &state); err != nil {
+		return fmt.Errorf("error parsing remote state: %w", err)
+	}
+
+	if state.Enabled == nil {
+		return fmt.Errorf("remote state is missing the enabled field")
+	}
+
+	var until time.Time
+	if state.Until != "" {
+		until, err = time.Parse(time.RFC3339, state.Until)
+		if err != nil {
+			return fmt.Errorf("invalid until time in remote state: %w", err)
+		}
+	}
+
+	m.stateMutex.Lock()
+	changed := m.enabled != *state.Enabled
+	m.enabled = *state.Enabled
+	m.maintenanceEndTime = until
+	m.stateMutex.Unlock()
+
+	m.stateETag = resp.Header.Get("ETag")
+
+	if changed {
+		m.log(LogLevelInfo, "Remote state changed maintenance mode to enabled=%t", *state.Enabled)
+	}
+
+	return nil
+}
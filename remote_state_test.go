@@ -0,0 +1,281 @@
+package traefik_maintenance_warden
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stateServer is a stand-in control plane serving a remote state document with an ETag
+type stateServer struct {
+	mu          sync.Mutex
+	body        string
+	etag        string
+	statusCode  int
+	requests    int
+	notModified int
+}
+
+func (s *stateServer) set(body, etag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.body, s.etag, s.statusCode = body, etag, http.StatusOK
+}
+
+func (s *stateServer) fail(statusCode int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statusCode = statusCode
+}
+
+func (s *stateServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *stateServer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+
+	if s.statusCode != http.StatusOK {
+		rw.WriteHeader(s.statusCode)
+		return
+	}
+
+	if s.etag != "" && req.Header.Get("If-None-Match") == s.etag {
+		s.notModified++
+		rw.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	rw.Header().Set("ETag", s.etag)
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Write([]byte(s.body))
+}
+
+// newRemoteStateTestMiddleware creates a middleware pointed at the given state URL without starting the poller
+func newRemoteStateTestMiddleware(t *testing.T, enabled bool, stateURL string) *MaintenanceBypass {
+	t.Helper()
+
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		Enabled:            enabled,
+	}, "remote-state-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	m := middleware.(*MaintenanceBypass)
+	m.stateURL = stateURL
+	m.stateClient = &http.Client{Timeout: time.Second}
+
+	return m
+}
+
+// TestPollRemoteState tests applying the remote state document
+func TestPollRemoteState(t *testing.T) {
+	server := &stateServer{}
+	server.set(`{"enabled":true,"until":"2025-01-05T04:00:00Z"}`, `"v1"`)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	m := newRemoteStateTestMiddleware(t, false, httpServer.URL)
+
+	if err := m.pollRemoteState(context.Background()); err != nil {
+		t.Fatalf("Error polling remote state: %v", err)
+	}
+
+	if !m.isMaintenanceEnabled(nil) {
+		t.Errorf("Expected maintenance mode to be enabled by remote state")
+	}
+
+	if end, ok := m.maintenanceEnd(time.Date(2025, 1, 5, 3, 0, 0, 0, time.UTC)); !ok || !end.Equal(time.Date(2025, 1, 5, 4, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected maintenance end from remote state, got %v (found=%t)", end, ok)
+	}
+
+	// An unchanged document is not transferred again
+	if err := m.pollRemoteState(context.Background()); err != nil {
+		t.Fatalf("Error polling remote state: %v", err)
+	}
+
+	if server.notModified != 1 {
+		t.Errorf("Expected a conditional request answered with 304, got %d", server.notModified)
+	}
+
+	// A new document is applied
+	server.set(`{"enabled":false}`, `"v2"`)
+	if err := m.pollRemoteState(context.Background()); err != nil {
+		t.Fatalf("Error polling remote state: %v", err)
+	}
+
+	if m.isMaintenanceEnabled(nil) {
+		t.Errorf("Expected maintenance mode to be disabled by remote state")
+	}
+}
+
+// TestPollRemoteStateErrors tests that failures keep the current state
+func TestPollRemoteStateErrors(t *testing.T) {
+	testCases := []struct {
+		name       string
+		body       string
+		statusCode int
+	}{
+		{"Server error", "", http.StatusInternalServerError},
+		{"Invalid JSON", `{"enabled":`, http.StatusOK},
+		{"Missing enabled field", `{"until":"2025-01-05T04:00:00Z"}`, http.StatusOK},
+		{"Invalid until time", `{"enabled":false,"until":"soon"}`, http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := &stateServer{}
+			server.set(tc.body, "")
+			if tc.statusCode != http.StatusOK {
+				server.fail(tc.statusCode)
+			}
+			httpServer := httptest.NewServer(server)
+			defer httpServer.Close()
+
+			// The configured enabled flag is the fail-safe default
+			m := newRemoteStateTestMiddleware(t, true, httpServer.URL)
+
+			if err := m.pollRemoteState(context.Background()); err == nil {
+				t.Errorf("Expected an error but got none")
+			}
+
+			if !m.isMaintenanceEnabled(nil) {
+				t.Errorf("Expected maintenance mode to keep the configured state")
+			}
+		})
+	}
+
+	// Connection errors are reported as well
+	m := newRemoteStateTestMiddleware(t, true, "http://127.0.0.1:1")
+	m.stateClient.Transport = &MockTransportWithError{}
+	if err := m.pollRemoteState(context.Background()); err == nil {
+		t.Errorf("Expected an error for an unreachable state URL")
+	}
+
+	m.stateClient.Transport = &MockTransportWithBodyError{}
+	if err := m.pollRemoteState(context.Background()); err == nil {
+		t.Errorf("Expected an error for an unreadable response body")
+	}
+
+	m.stateURL = "http://127.0.0.1:1/\x7f"
+	if err := m.pollRemoteState(context.Background()); err == nil {
+		t.Errorf("Expected an error for an invalid request URL")
+	}
+}
+
+// TestRemoteStateInterval tests that the state URL is polled again on every interval, even after failures
+func TestRemoteStateInterval(t *testing.T) {
+	var polls int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&polls, 1)
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := &MaintenanceBypass{
+		stateURL:          server.URL,
+		statePollInterval: time.Millisecond,
+		stateClient:       &http.Client{},
+	}
+	go m.watchRemoteState(ctx)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&polls) < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if count := atomic.LoadInt32(&polls); count < 3 {
+		t.Errorf("Expected repeated polls of the state URL, got %d", count)
+	}
+}
+
+// TestRemoteStateWatcher tests that New starts the poller and that it stops with the context
+func TestRemoteStateWatcher(t *testing.T) {
+	server := &stateServer{}
+	server.set(`{"enabled":true}`, `"v1"`)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	middleware, err := New(ctx, nextHandler, &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		Enabled:            false,
+		StateURL:           httpServer.URL,
+		StatePollInterval:  1,
+	}, "remote-state-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	m := middleware.(*MaintenanceBypass)
+
+	// The first poll happens immediately
+	deadline := time.Now().Add(2 * time.Second)
+	for !m.isMaintenanceEnabled(nil) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected remote state to enable maintenance mode")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The next poll happens after the interval
+	deadline = time.Now().Add(3 * time.Second)
+	for server.count() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if requests := server.count(); requests < 2 {
+		t.Fatalf("Expected the poller started by New to repeat, got %d requests", requests)
+	}
+
+	cancel()
+
+	// Let a poll that was already running finish, then no further polls may start
+	time.Sleep(100 * time.Millisecond)
+	stopped := server.count()
+	time.Sleep(1500 * time.Millisecond)
+
+	if requests := server.count(); requests != stopped {
+		t.Errorf("Expected polling to stop when the context is cancelled, got %d more requests", requests-stopped)
+	}
+}
+
+// TestInvalidStateURL tests validation of the state URL
+func TestInvalidStateURL(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	for _, stateURL := range []string{"://invalid", "control-plane/state"} {
+		_, err := New(context.Background(), nextHandler, &Config{
+			MaintenanceContent: "<html><body>Maintenance</body></html>",
+			StateURL:           stateURL,
+		}, "remote-state-test")
+		if err == nil {
+			t.Errorf("Expected an error for state URL %q", stateURL)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package traefik_maintenance_warden
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net"
+	"net/http"
+)
+
+const (
+	// passThroughKeySourceIP assigns requests by client IP
+	passThroughKeySourceIP = "ip"
+	// passThroughKeySourceCookie assigns requests by the value of a cookie
+	passThroughKeySourceCookie = "cookie"
+	// passThroughKeySourceHeader assigns requests by the value of a header
+	passThroughKeySourceHeader = "header"
+)
+
+// validatePassThrough validates the gradual rollout configuration
+func validatePassThrough(percent int, source, name string) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("passThroughPercent must be between 0 and 100, got %d", percent)
+	}
+
+	switch source {
+	case "", passThroughKeySourceIP:
+	case passThroughKeySourceCookie, passThroughKeySourceHeader:
+		if name == "" {
+			return fmt.Errorf("passThroughKeyName is required when passThroughKeySource is %q", source)
+		}
+	default:
+		return fmt.Errorf("passThroughKeySource must be %q, %q or %q", passThroughKeySourceIP, passThroughKeySourceCookie, passThroughKeySourceHeader)
+	}
+
+	return nil
+}
+
+// passThroughKey returns the value used to assign a request to a rollout bucket.
+// Requests without the configured cookie or header fall back to the client IP.
+func (m *MaintenanceBypass) passThroughKey(req *http.Request) string {
+	switch m.passThroughKeySource {
+	case passThroughKeySourceCookie:
+		if cookie, err := req.Cookie(m.passThroughKeyName); err == nil && cookie.Value != "" {
+			return cookie.Value
+		}
+	case passThroughKeySourceHeader:
+		if value := req.Header.Get(m.passThroughKeyName); value != "" {
+			return value
+		}
+	}
+
+	return m.clientIP(req)
+}
+
+// isPassThroughSelected reports whether the request falls into the share of traffic let through.
+// Assignment is sticky: the same key always lands in the same bucket, and raising the percentage
+// only adds buckets, so users who were let through keep being let through.
+func (m *MaintenanceBypass) isPassThroughSelected(req *http.Request) bool {
+	if m.passThroughPercent <= 0 {
+		return false
+	}
+	if m.passThroughPercent >= 100 {
+		return true
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(m.name))
+	hash.Write([]byte{0})
+	hash.Write([]byte(m.passThroughKey(req)))
+
+	return int(hash.Sum32()%100) < m.passThroughPercent
+}
+
+// remoteIP returns the IP address of the immediate peer of the request
+func remoteIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
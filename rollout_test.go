@@ -0,0 +1,163 @@
+package traefik_maintenance_warden
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newRolloutTestMiddleware creates a middleware with a gradual rollout configured
+func newRolloutTestMiddleware(t *testing.T, percent int, source, name string) *MaintenanceBypass {
+	t.Helper()
+
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent:   "<html><body>Maintenance</body></html>",
+		Enabled:              true,
+		PassThroughPercent:   percent,
+		PassThroughKeySource: source,
+		PassThroughKeyName:   name,
+	}, "rollout-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	return middleware.(*MaintenanceBypass)
+}
+
+// TestPassThroughPercent tests that roughly the configured share of users is let through
+func TestPassThroughPercent(t *testing.T) {
+	for _, percent := range []int{0, 10, 50, 100} {
+		t.Run(fmt.Sprintf("%d percent", percent), func(t *testing.T) {
+			m := newRolloutTestMiddleware(t, percent, "header", "X-User-ID")
+
+			passed := 0
+			for i := 0; i < 1000; i++ {
+				req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+				req.Header.Set("X-User-ID", fmt.Sprintf("user-%d", i))
+
+				recorder := httptest.NewRecorder()
+				m.ServeHTTP(recorder, req)
+				if recorder.Code == http.StatusOK {
+					passed++
+				}
+			}
+
+			// Allow some deviation from the exact share
+			expected := percent * 10
+			if passed < expected-50 || passed > expected+50 {
+				t.Errorf("Expected about %d of 1000 requests to pass, got %d", expected, passed)
+			}
+		})
+	}
+}
+
+// TestPassThroughSticky tests that assignment is sticky and grows monotonically with the percentage
+func TestPassThroughSticky(t *testing.T) {
+	low := newRolloutTestMiddleware(t, 10, "cookie", "session")
+	high := newRolloutTestMiddleware(t, 50, "cookie", "session")
+
+	for i := 0; i < 200; i++ {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+		req.AddCookie(&http.Cookie{Name: "session", Value: fmt.Sprintf("session-%d", i)})
+
+		selected := low.isPassThroughSelected(req)
+		if selected != low.isPassThroughSelected(req) {
+			t.Fatalf("Expected the same assignment for repeated requests of session-%d", i)
+		}
+
+		if selected && !high.isPassThroughSelected(req) {
+			t.Errorf("Expected session-%d let through at 10%% to stay let through at 50%%", i)
+		}
+	}
+}
+
+// TestPassThroughKey tests the key sources used for assignment
+func TestPassThroughKey(t *testing.T) {
+	testCases := []struct {
+		name     string
+		source   string
+		keyName  string
+		setup    func(req *http.Request)
+		expected string
+	}{
+		{"Client IP", "ip", "", func(req *http.Request) {}, "192.0.2.1"},
+		{"Default is client IP", "", "", func(req *http.Request) {}, "192.0.2.1"},
+		{"Cookie", "cookie", "session", func(req *http.Request) {
+			req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+		}, "abc"},
+		{"Missing cookie falls back to client IP", "cookie", "session", func(req *http.Request) {}, "192.0.2.1"},
+		{"Header", "header", "X-User-ID", func(req *http.Request) {
+			req.Header.Set("X-User-ID", "42")
+		}, "42"},
+		{"Missing header falls back to client IP", "header", "X-User-ID", func(req *http.Request) {}, "192.0.2.1"},
+		{"Forwarded client IP from untrusted peer is ignored", "ip", "", func(req *http.Request) {
+			req.Header.Set("X-Forwarded-For", "198.51.100.1")
+		}, "192.0.2.1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := newRolloutTestMiddleware(t, 50, tc.source, tc.keyName)
+
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			tc.setup(req)
+
+			if key := m.passThroughKey(req); key != tc.expected {
+				t.Errorf("Expected key %q, got %q", tc.expected, key)
+			}
+		})
+	}
+
+	// Behind a trusted proxy the forwarded client IP is the key
+	m := newRolloutTestMiddleware(t, 50, "ip", "")
+	m.trustedProxies, _ = parseCIDRs([]string{"192.0.2.1"})
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+
+	if key := m.passThroughKey(req); key != "198.51.100.1" {
+		t.Errorf("Expected forwarded client IP as key, got %q", key)
+	}
+}
+
+// TestPassThroughConfigValidation tests validation of the rollout configuration
+func TestPassThroughConfigValidation(t *testing.T) {
+	testCases := []struct {
+		name    string
+		percent int
+		source  string
+		keyName string
+	}{
+		{"Negative percent", -1, "ip", ""},
+		{"Percent above 100", 101, "ip", ""},
+		{"Unknown source", 10, "query", ""},
+		{"Cookie without name", 10, "cookie", ""},
+		{"Header without name", 10, "header", ""},
+	}
+
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(context.Background(), nextHandler, &Config{
+				MaintenanceContent:   "<html><body>Maintenance</body></html>",
+				PassThroughPercent:   tc.percent,
+				PassThroughKeySource: tc.source,
+				PassThroughKeyName:   tc.keyName,
+			}, "rollout-test")
+			if err == nil {
+				t.Errorf("Expected an error but got none")
+			}
+		})
+	}
+}
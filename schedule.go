@@ -0,0 +1,180 @@
+package traefik_maintenance_warden
+
+import (
+	"fmt"
+	"time"
+)
+
+// scheduleLocalTimeLayout is the layout accepted for window times that carry no UTC offset.
+// Such times are interpreted in the schedule's time zone.
+const scheduleLocalTimeLayout = "2006-01-02T15:04:05"
+
+// ScheduleConfig holds the maintenance windows during which maintenance mode is active
+type ScheduleConfig struct {
+	// TimeZone is the IANA time zone used for window times without a UTC offset (default: UTC)
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Windows are absolute maintenance windows
+	Windows []WindowConfig `json:"windows,omitempty"`
+
+	// Recurring are maintenance windows that repeat on a cron schedule
+	Recurring []RecurringWindowConfig `json:"recurring,omitempty"`
+}
+
+// WindowConfig defines a single absolute maintenance window
+type WindowConfig struct {
+	// Start is the RFC3339 start time of the window (inclusive)
+	Start string `json:"start,omitempty"`
+
+	// End is the RFC3339 end time of the window (exclusive)
+	End string `json:"end,omitempty"`
+}
+
+// RecurringWindowConfig defines a maintenance window that repeats on a cron schedule
+type RecurringWindowConfig struct {
+	// Cron is a five-field cron expression (minute hour day-of-month month day-of-week)
+	// marking the start of each window, evaluated in the schedule time zone
+	Cron string `json:"cron,omitempty"`
+
+	// Duration is how long each window lasts, as a Go duration string (e.g. "2h")
+	Duration string `json:"duration,omitempty"`
+}
+
+// maintenanceWindow is a parsed maintenance window
+type maintenanceWindow struct {
+	start time.Time
+	end   time.Time
+}
+
+// contains reports whether t falls within the window
+func (w maintenanceWindow) contains(t time.Time) bool {
+	return !t.Before(w.start) && t.Before(w.end)
+}
+
+// recurringWindow is a parsed recurring maintenance window
+type recurringWindow struct {
+	cron     *cronSchedule
+	duration time.Duration
+}
+
+// schedule holds the parsed maintenance windows
+type schedule struct {
+	location  *time.Location
+	windows   []maintenanceWindow
+	recurring []recurringWindow
+}
+
+// newSchedule parses the schedule configuration, returning nil if no windows are configured
+func newSchedule(config ScheduleConfig) (*schedule, error) {
+	if len(config.Windows) == 0 && len(config.Recurring) == 0 {
+		return nil, nil
+	}
+
+	location := time.UTC
+	if config.TimeZone != "" {
+		loc, err := time.LoadLocation(config.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule time zone %q: %w", config.TimeZone, err)
+		}
+		location = loc
+	}
+
+	s := &schedule{location: location}
+	for i, window := range config.Windows {
+		start, err := parseScheduleTime(window.Start, location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start time in schedule window %d: %w", i, err)
+		}
+
+		end, err := parseScheduleTime(window.End, location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end time in schedule window %d: %w", i, err)
+		}
+
+		if !end.After(start) {
+			return nil, fmt.Errorf("schedule window %d must end after it starts", i)
+		}
+
+		s.windows = append(s.windows, maintenanceWindow{start: start, end: end})
+	}
+
+	for i, recurring := range config.Recurring {
+		cron, err := parseCron(recurring.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression in recurring window %d: %w", i, err)
+		}
+
+		duration, err := time.ParseDuration(recurring.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration in recurring window %d: %w", i, err)
+		}
+
+		if duration <= 0 {
+			return nil, fmt.Errorf("recurring window %d must have a positive duration", i)
+		}
+
+		s.recurring = append(s.recurring, recurringWindow{cron: cron, duration: duration})
+	}
+
+	return s, nil
+}
+
+// parseScheduleTime parses an RFC3339 time, falling back to a local time in the given location
+func parseScheduleTime(value string, location *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, fmt.Errorf("time must not be empty")
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.ParseInLocation(scheduleLocalTimeLayout, value, location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("time %q is not in RFC3339 format", value)
+	}
+
+	return t, nil
+}
+
+// activeWindow returns the window containing now, if any
+func (s *schedule) activeWindow(now time.Time) (maintenanceWindow, bool) {
+	for _, window := range s.windows {
+		if window.contains(now) {
+			return window, true
+		}
+	}
+
+	for _, recurring := range s.recurring {
+		// The earliest activation within the last duration is the one that covers now, if any
+		start := recurring.cron.next(now.Add(-recurring.duration), s.location)
+		if !start.IsZero() && !start.After(now) {
+			return maintenanceWindow{start: start, end: start.Add(recurring.duration)}, true
+		}
+	}
+
+	return maintenanceWindow{}, false
+}
+
+// nextWindow returns the earliest window starting after now, if any
+func (s *schedule) nextWindow(now time.Time) (maintenanceWindow, bool) {
+	var next maintenanceWindow
+	found := false
+
+	for _, window := range s.windows {
+		if window.start.After(now) && (!found || window.start.Before(next.start)) {
+			next = window
+			found = true
+		}
+	}
+
+	for _, recurring := range s.recurring {
+		start := recurring.cron.next(now, s.location)
+		if !start.IsZero() && (!found || start.Before(next.start)) {
+			next = maintenanceWindow{start: start, end: start.Add(recurring.duration)}
+			found = true
+		}
+	}
+
+	return next, found
+}
@@ -0,0 +1,335 @@
+package traefik_maintenance_warden
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestNewSchedule tests parsing of the schedule configuration
+func TestNewSchedule(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        ScheduleConfig
+		expectNil     bool
+		expectedError string
+	}{
+		{
+			name:      "Empty schedule",
+			config:    ScheduleConfig{},
+			expectNil: true,
+		},
+		{
+			name: "Valid RFC3339 window",
+			config: ScheduleConfig{
+				Windows: []WindowConfig{{Start: "2025-01-05T02:00:00Z", End: "2025-01-05T04:00:00Z"}},
+			},
+		},
+		{
+			name: "Valid local window with time zone",
+			config: ScheduleConfig{
+				TimeZone: "Europe/Berlin",
+				Windows:  []WindowConfig{{Start: "2025-01-05T02:00:00", End: "2025-01-05T04:00:00"}},
+			},
+		},
+		{
+			name: "Invalid time zone",
+			config: ScheduleConfig{
+				TimeZone: "Mars/Olympus_Mons",
+				Windows:  []WindowConfig{{Start: "2025-01-05T02:00:00Z", End: "2025-01-05T04:00:00Z"}},
+			},
+			expectedError: "invalid schedule time zone",
+		},
+		{
+			name: "Missing start",
+			config: ScheduleConfig{
+				Windows: []WindowConfig{{End: "2025-01-05T04:00:00Z"}},
+			},
+			expectedError: "invalid start time",
+		},
+		{
+			name: "Invalid end",
+			config: ScheduleConfig{
+				Windows: []WindowConfig{{Start: "2025-01-05T02:00:00Z", End: "tomorrow"}},
+			},
+			expectedError: "invalid end time",
+		},
+		{
+			name: "End before start",
+			config: ScheduleConfig{
+				Windows: []WindowConfig{{Start: "2025-01-05T04:00:00Z", End: "2025-01-05T02:00:00Z"}},
+			},
+			expectedError: "must end after it starts",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := newSchedule(tc.config)
+
+			if tc.expectedError != "" {
+				if err == nil {
+					t.Fatalf("Expected error containing %q, got nil", tc.expectedError)
+				}
+				if !strings.Contains(err.Error(), tc.expectedError) {
+					t.Errorf("Expected error containing %q, got %v", tc.expectedError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Did not expect an error but got: %v", err)
+			}
+
+			if tc.expectNil && s != nil {
+				t.Errorf("Expected nil schedule, got %+v", s)
+			}
+
+			if !tc.expectNil && s == nil {
+				t.Errorf("Expected schedule, got nil")
+			}
+		})
+	}
+}
+
+// TestScheduleTimeZone tests that local window times are interpreted in the schedule time zone
+func TestScheduleTimeZone(t *testing.T) {
+	s, err := newSchedule(ScheduleConfig{
+		TimeZone: "Europe/Berlin",
+		Windows:  []WindowConfig{{Start: "2025-01-05T02:00:00", End: "2025-01-05T04:00:00"}},
+	})
+	if err != nil {
+		t.Fatalf("Error creating schedule: %v", err)
+	}
+
+	// 02:30 in Berlin (CET, UTC+1) is 01:30 UTC
+	if _, active := s.activeWindow(time.Date(2025, 1, 5, 1, 30, 0, 0, time.UTC)); !active {
+		t.Errorf("Expected window to be active at 01:30 UTC")
+	}
+
+	// 03:30 UTC is 04:30 in Berlin, after the window
+	if _, active := s.activeWindow(time.Date(2025, 1, 5, 3, 30, 0, 0, time.UTC)); active {
+		t.Errorf("Expected window to be inactive at 03:30 UTC")
+	}
+}
+
+// TestScheduledMaintenance tests that the middleware follows the configured schedule
+func TestScheduledMaintenance(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	testCases := []struct {
+		name           string
+		enabled        bool
+		now            time.Time
+		expectedStatus int
+	}{
+		{
+			name:           "Before first window",
+			enabled:        true,
+			now:            time.Date(2025, 1, 5, 1, 59, 59, 0, time.UTC),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "At start of first window",
+			enabled:        true,
+			now:            time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC),
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "At end of first window",
+			enabled:        true,
+			now:            time.Date(2025, 1, 5, 4, 0, 0, 0, time.UTC),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Inside second window",
+			enabled:        true,
+			now:            time.Date(2025, 1, 12, 3, 0, 0, 0, time.UTC),
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "Inside window but disabled",
+			enabled:        false,
+			now:            time.Date(2025, 1, 5, 3, 0, 0, 0, time.UTC),
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &Config{
+				MaintenanceContent: "<html><body>Maintenance</body></html>",
+				Enabled:            tc.enabled,
+				Schedule: ScheduleConfig{
+					Windows: []WindowConfig{
+						{Start: "2025-01-05T02:00:00Z", End: "2025-01-05T04:00:00Z"},
+						{Start: "2025-01-12T02:00:00Z", End: "2025-01-12T04:00:00Z"},
+					},
+				},
+			}
+
+			middleware, err := New(context.Background(), nextHandler, cfg, "schedule-test")
+			if err != nil {
+				t.Fatalf("Error creating middleware: %v", err)
+			}
+
+			m := middleware.(*MaintenanceBypass)
+			m.now = func() time.Time { return tc.now }
+
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+			m.ServeHTTP(recorder, req)
+
+			if recorder.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, recorder.Code)
+			}
+		})
+	}
+}
+
+// TestInvalidScheduleConfig tests that an invalid schedule fails at config time
+func TestInvalidScheduleConfig(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	cfg := &Config{
+		MaintenanceContent: "<html><body>Maintenance</body></html>",
+		Enabled:            true,
+		Schedule: ScheduleConfig{
+			Windows: []WindowConfig{{Start: "not-a-time", End: "2025-01-05T04:00:00Z"}},
+		},
+	}
+
+	_, err := New(context.Background(), nextHandler, cfg, "schedule-test")
+	if err == nil {
+		t.Fatalf("Expected error for invalid schedule, got nil")
+	}
+
+	if !strings.Contains(err.Error(), "invalid schedule") {
+		t.Errorf("Expected error to mention invalid schedule, got: %v", err)
+	}
+}
+
+// TestRecurringSchedule tests recurring windows and the next upcoming window
+func TestRecurringSchedule(t *testing.T) {
+	s, err := newSchedule(ScheduleConfig{
+		TimeZone: "Europe/Berlin",
+		Windows:  []WindowConfig{{Start: "2025-01-08T12:00:00", End: "2025-01-08T13:00:00"}},
+		Recurring: []RecurringWindowConfig{
+			{Cron: "0 2 * * sun", Duration: "2h"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Error creating schedule: %v", err)
+	}
+
+	berlin := s.location
+
+	testCases := []struct {
+		name          string
+		now           time.Time
+		expectActive  bool
+		expectedStart time.Time
+		expectedEnd   time.Time
+	}{
+		{
+			name:          "Start of recurring window",
+			now:           time.Date(2025, 1, 5, 2, 0, 0, 0, berlin),
+			expectActive:  true,
+			expectedStart: time.Date(2025, 1, 5, 2, 0, 0, 0, berlin),
+			expectedEnd:   time.Date(2025, 1, 5, 4, 0, 0, 0, berlin),
+		},
+		{
+			name:          "Inside recurring window",
+			now:           time.Date(2025, 1, 12, 3, 59, 59, 0, berlin),
+			expectActive:  true,
+			expectedStart: time.Date(2025, 1, 12, 2, 0, 0, 0, berlin),
+			expectedEnd:   time.Date(2025, 1, 12, 4, 0, 0, 0, berlin),
+		},
+		{
+			name:         "End of recurring window",
+			now:          time.Date(2025, 1, 12, 4, 0, 0, 0, berlin),
+			expectActive: false,
+		},
+		{
+			name:         "Other weekday",
+			now:          time.Date(2025, 1, 6, 3, 0, 0, 0, berlin),
+			expectActive: false,
+		},
+		{
+			name:          "Absolute window alongside recurring",
+			now:           time.Date(2025, 1, 8, 12, 30, 0, 0, berlin),
+			expectActive:  true,
+			expectedStart: time.Date(2025, 1, 8, 12, 0, 0, 0, berlin),
+			expectedEnd:   time.Date(2025, 1, 8, 13, 0, 0, 0, berlin),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			window, active := s.activeWindow(tc.now)
+			if active != tc.expectActive {
+				t.Fatalf("Expected active=%t, got %t", tc.expectActive, active)
+			}
+
+			if active && (!window.start.Equal(tc.expectedStart) || !window.end.Equal(tc.expectedEnd)) {
+				t.Errorf("Expected window %v-%v, got %v-%v", tc.expectedStart, tc.expectedEnd, window.start, window.end)
+			}
+		})
+	}
+
+	// Around daylight saving transitions the window keeps its length in absolute time
+	for _, tc := range []struct {
+		name          string
+		now           time.Time
+		expectedStart time.Time
+	}{
+		{"Spring forward Sunday starts at the transition", time.Date(2026, 3, 29, 1, 30, 0, 0, time.UTC), time.Date(2026, 3, 29, 1, 0, 0, 0, time.UTC)},
+		{"Fall back Sunday starts at the first 02:00", time.Date(2026, 10, 25, 0, 15, 0, 0, time.UTC), time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC)},
+	} {
+		window, active := s.activeWindow(tc.now)
+		if !active || !window.start.Equal(tc.expectedStart) || !window.end.Equal(tc.expectedStart.Add(2*time.Hour)) {
+			t.Errorf("%s: expected window from %v for 2h, got %v-%v (active=%t)", tc.name, tc.expectedStart, window.start, window.end, active)
+		}
+	}
+
+	// The absolute window on Wednesday comes before the next Sunday
+	next, ok := s.nextWindow(time.Date(2025, 1, 6, 0, 0, 0, 0, berlin))
+	if !ok || !next.start.Equal(time.Date(2025, 1, 8, 12, 0, 0, 0, berlin)) {
+		t.Errorf("Expected next window to start at the absolute window, got %v (found=%t)", next.start, ok)
+	}
+
+	// After the absolute window, the recurring window is next
+	next, ok = s.nextWindow(time.Date(2025, 1, 9, 0, 0, 0, 0, berlin))
+	if !ok || !next.start.Equal(time.Date(2025, 1, 12, 2, 0, 0, 0, berlin)) || !next.end.Equal(time.Date(2025, 1, 12, 4, 0, 0, 0, berlin)) {
+		t.Errorf("Expected next window on 2025-01-12 02:00-04:00, got %v-%v (found=%t)", next.start, next.end, ok)
+	}
+}
+
+// TestInvalidRecurringSchedule tests validation of recurring windows
+func TestInvalidRecurringSchedule(t *testing.T) {
+	testCases := []struct {
+		name          string
+		recurring     RecurringWindowConfig
+		expectedError string
+	}{
+		{"Invalid cron", RecurringWindowConfig{Cron: "0 2 * *", Duration: "2h"}, "invalid cron expression"},
+		{"Invalid duration", RecurringWindowConfig{Cron: "0 2 * * 0", Duration: "two hours"}, "invalid duration"},
+		{"Zero duration", RecurringWindowConfig{Cron: "0 2 * * 0", Duration: "0s"}, "positive duration"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := newSchedule(ScheduleConfig{Recurring: []RecurringWindowConfig{tc.recurring}})
+			if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+				t.Errorf("Expected error containing %q, got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
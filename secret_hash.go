@@ -0,0 +1,168 @@
+package traefik_maintenance_warden
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// secretHashSHA256 is the prefix of a SHA-256 hashed secret: sha256:<hex digest>
+	secretHashSHA256 = "sha256"
+	// secretHashPBKDF2 is the prefix of a salted PBKDF2 hashed secret: pbkdf2-sha256:<iterations>:<hex salt>:<hex key>
+	secretHashPBKDF2 = "pbkdf2-sha256"
+
+	// maxPBKDF2Iterations bounds the cost of verifying a presented value, which any client can trigger
+	maxPBKDF2Iterations = 1000000
+	// maxRejectedSecrets bounds the number of rejected value digests remembered by a matcher
+	maxRejectedSecrets = 1024
+)
+
+// secretMatcher compares presented values against a configured secret in constant time.
+// The secret is either a plaintext value or a SHA-256 or salted PBKDF2-SHA256 hash of it.
+type secretMatcher struct {
+	// digest is the SHA-256 digest of the secret, or the derived key for PBKDF2
+	digest     []byte
+	iterations int
+	salt       []byte
+
+	// verified caches the SHA-256 digest of the last value that passed PBKDF2 verification and
+	// rejected the digests of values that failed it, so the expensive key derivation only runs for new values
+	mutex    sync.Mutex
+	verified []byte
+	rejected map[[sha256.Size]byte]struct{}
+
+	// derive serializes key derivations so that requests with invalid values occupy at most one CPU
+	derive sync.Mutex
+}
+
+// newSecretMatcher creates a matcher for a plaintext secret, or for a hashed secret if one is given
+func newSecretMatcher(plain string, hashed string) (*secretMatcher, error) {
+	if hashed == "" {
+		digest := sha256.Sum256([]byte(plain))
+		return &secretMatcher{digest: digest[:]}, nil
+	}
+
+	algorithm, rest, _ := strings.Cut(hashed, ":")
+	switch algorithm {
+	case secretHashSHA256:
+		digest, err := hex.DecodeString(rest)
+		if err != nil || len(digest) != sha256.Size {
+			return nil, fmt.Errorf("sha256 hash must be 64 hexadecimal characters")
+		}
+		return &secretMatcher{digest: digest}, nil
+	case secretHashPBKDF2:
+		parts := strings.Split(rest, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("pbkdf2-sha256 hash must have the form pbkdf2-sha256:<iterations>:<hex salt>:<hex key>")
+		}
+
+		iterations, err := strconv.Atoi(parts[0])
+		if err != nil || iterations < 1 {
+			return nil, fmt.Errorf("invalid pbkdf2-sha256 iterations %q", parts[0])
+		}
+		if iterations > maxPBKDF2Iterations {
+			return nil, fmt.Errorf("pbkdf2-sha256 iterations must not exceed %d, got %d", maxPBKDF2Iterations, iterations)
+		}
+
+		salt, err := hex.DecodeString(parts[1])
+		if err != nil || len(salt) == 0 {
+			return nil, fmt.Errorf("invalid pbkdf2-sha256 salt")
+		}
+
+		key, err := hex.DecodeString(parts[2])
+		if err != nil || len(key) == 0 {
+			return nil, fmt.Errorf("invalid pbkdf2-sha256 key")
+		}
+
+		return &secretMatcher{
+			digest:     key,
+			iterations: iterations,
+			salt:       salt,
+			rejected:   make(map[[sha256.Size]byte]struct{}),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported hash algorithm %q, expected %s or %s", algorithm, secretHashSHA256, secretHashPBKDF2)
+	}
+}
+
+// matches reports whether the value matches the secret, comparing in constant time
+func (s *secretMatcher) matches(value string) bool {
+	if s == nil {
+		return false
+	}
+
+	valueDigest := sha256.Sum256([]byte(value))
+
+	if s.iterations == 0 {
+		return subtle.ConstantTimeCompare(valueDigest[:], s.digest) == 1
+	}
+
+	s.mutex.Lock()
+	verified := s.verified
+	_, rejected := s.rejected[valueDigest]
+	s.mutex.Unlock()
+
+	if verified != nil && subtle.ConstantTimeCompare(valueDigest[:], verified) == 1 {
+		return true
+	}
+
+	if rejected {
+		return false
+	}
+
+	s.derive.Lock()
+	key := pbkdf2SHA256([]byte(value), s.salt, s.iterations, len(s.digest))
+	s.derive.Unlock()
+
+	if subtle.ConstantTimeCompare(key, s.digest) != 1 {
+		s.mutex.Lock()
+		// Start over rather than grow without bound when many different values are presented
+		if len(s.rejected) >= maxRejectedSecrets {
+			s.rejected = make(map[[sha256.Size]byte]struct{})
+		}
+		s.rejected[valueDigest] = struct{}{}
+		s.mutex.Unlock()
+		return false
+	}
+
+	s.mutex.Lock()
+	s.verified = valueDigest[:]
+	s.mutex.Unlock()
+
+	return true
+}
+
+// pbkdf2SHA256 derives a key from a password with PBKDF2-HMAC-SHA256 as defined in RFC 8018
+func pbkdf2SHA256(password []byte, salt []byte, iterations int, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	blocks := (keyLen + sha256.Size - 1) / sha256.Size
+
+	key := make([]byte, 0, blocks*sha256.Size)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write([]byte{byte(block >> 24), byte(block >> 16), byte(block >> 8), byte(block)})
+		u := prf.Sum(nil)
+
+		t := make([]byte, len(u))
+		copy(t, u)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+
+		key = append(key, t...)
+	}
+
+	return key[:keyLen]
+}
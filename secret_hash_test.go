@@ -0,0 +1,179 @@
+package traefik_maintenance_warden
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestPBKDF2SHA256 tests the key derivation against the published test vectors
+func TestPBKDF2SHA256(t *testing.T) {
+	testCases := []struct {
+		iterations int
+		keyLen     int
+		expected   string
+	}{
+		{1, 32, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{2, 32, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+		{4096, 32, "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+		{1, 40, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b4dbf3a2f3dad3377"},
+	}
+
+	for _, tc := range testCases {
+		key := hex.EncodeToString(pbkdf2SHA256([]byte("password"), []byte("salt"), tc.iterations, tc.keyLen))
+		if key != tc.expected {
+			t.Errorf("Expected key %s for %d iterations, got %s", tc.expected, tc.iterations, key)
+		}
+	}
+}
+
+// TestSecretMatcher tests matching against plaintext and hashed secrets
+func TestSecretMatcher(t *testing.T) {
+	testCases := []struct {
+		name    string
+		plain   string
+		hashed  string
+		value   string
+		matches bool
+	}{
+		{"Plaintext match", "team-secret", "", "team-secret", true},
+		{"Plaintext mismatch", "team-secret", "", "team-secreT", false},
+		{"SHA-256 match", "ignored", "sha256:7509900f69b7d4f018b111f107a6c7fc92e3664ccaff6ed2c397bf02f5afe725", "team-secret", true},
+		{"SHA-256 mismatch", "", "sha256:7509900f69b7d4f018b111f107a6c7fc92e3664ccaff6ed2c397bf02f5afe725", "ignored", false},
+		{"PBKDF2 match", "", "pbkdf2-sha256:1000:73616c74:6d77acd9bd2c487f0b0c1fcad8c1e391b28002d801859e8783f8f9d659904cc3", "team-secret", true},
+		{"PBKDF2 mismatch", "", "pbkdf2-sha256:1000:73616c74:6d77acd9bd2c487f0b0c1fcad8c1e391b28002d801859e8783f8f9d659904cc3", "wrong", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			matcher, err := newSecretMatcher(tc.plain, tc.hashed)
+			if err != nil {
+				t.Fatalf("Error creating matcher: %v", err)
+			}
+
+			// Match twice so the PBKDF2 cache is exercised
+			for i := 0; i < 2; i++ {
+				if matches := matcher.matches(tc.value); matches != tc.matches {
+					t.Errorf("Expected match %t, got %t", tc.matches, matches)
+				}
+			}
+		})
+	}
+
+	var nilMatcher *secretMatcher
+	if nilMatcher.matches("") {
+		t.Errorf("Expected a nil matcher to match nothing")
+	}
+}
+
+// TestSecretMatcherRejectedCache tests that rejected values are remembered without growing without bound
+func TestSecretMatcherRejectedCache(t *testing.T) {
+	matcher, err := newSecretMatcher("", "pbkdf2-sha256:1:73616c74:120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b")
+	if err != nil {
+		t.Fatalf("Error creating matcher: %v", err)
+	}
+
+	if matcher.matches("wrong") {
+		t.Fatalf("Expected a wrong value not to match")
+	}
+
+	// A remembered rejection is answered without deriving the key again
+	if matcher.matches("wrong") || len(matcher.rejected) != 1 {
+		t.Errorf("Expected the wrong value to be remembered once, got %d remembered rejections", len(matcher.rejected))
+	}
+
+	for i := 0; i < maxRejectedSecrets; i++ {
+		matcher.matches(fmt.Sprintf("wrong-%d", i))
+	}
+
+	if len(matcher.rejected) > maxRejectedSecrets {
+		t.Errorf("Expected at most %d remembered rejections, got %d", maxRejectedSecrets, len(matcher.rejected))
+	}
+
+	if !matcher.matches("password") {
+		t.Errorf("Expected the correct value to match")
+	}
+}
+
+// TestSecretMatcherErrors tests validation of hashed secrets
+func TestSecretMatcherErrors(t *testing.T) {
+	for _, hashed := range []string{
+		"md5:5f4dcc3b5aa765d61d8327deb882cf99",
+		"sha256:abcd",
+		"sha256:not-hex",
+		"pbkdf2-sha256:1000:73616c74",
+		"pbkdf2-sha256:0:73616c74:6d77",
+		"pbkdf2-sha256:1000001:73616c74:6d77",
+		"pbkdf2-sha256:many:73616c74:6d77",
+		"pbkdf2-sha256:1000::6d77",
+		"pbkdf2-sha256:1000:73616c74:zz",
+	} {
+		if _, err := newSecretMatcher("", hashed); err == nil {
+			t.Errorf("Expected an error for %q", hashed)
+		}
+	}
+}
+
+// TestSecretMatcherRecommendedIterations tests that the OWASP recommended PBKDF2 cost is accepted
+func TestSecretMatcherRecommendedIterations(t *testing.T) {
+	matcher, err := newSecretMatcher("", "pbkdf2-sha256:600000:73616c74:24b5f61213010fd3a6a0025d0a9996403f76fc07d6b00113418093b9dd247769")
+	if err != nil {
+		t.Fatalf("Error creating matcher: %v", err)
+	}
+
+	if !matcher.matches("team-secret") {
+		t.Errorf("Expected the correct value to match")
+	}
+}
+
+// TestBypassHeaderValueHash tests bypass with a hashed header value
+func TestBypassHeaderValueHash(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	middleware, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent:    "<html><body>Maintenance</body></html>",
+		Enabled:               true,
+		BypassHeader:          "X-Maintenance-Bypass",
+		BypassHeaderValue:     "true",
+		BypassHeaderValueHash: "sha256:7509900f69b7d4f018b111f107a6c7fc92e3664ccaff6ed2c397bf02f5afe725",
+	}, "secret-hash-test")
+	if err != nil {
+		t.Fatalf("Error creating middleware: %v", err)
+	}
+
+	testCases := []struct {
+		name           string
+		value          string
+		expectedStatus int
+	}{
+		{"Value matching the hash", "team-secret", http.StatusOK},
+		{"Plaintext value is ignored when a hash is set", "true", http.StatusServiceUnavailable},
+		{"The hash itself is not accepted", "7509900f69b7d4f018b111f107a6c7fc92e3664ccaff6ed2c397bf02f5afe725", http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+			req.Header.Set("X-Maintenance-Bypass", tc.value)
+
+			recorder := httptest.NewRecorder()
+			middleware.ServeHTTP(recorder, req)
+
+			if recorder.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, recorder.Code)
+			}
+		})
+	}
+
+	if _, err := New(context.Background(), nextHandler, &Config{
+		MaintenanceContent:    "<html><body>Maintenance</body></html>",
+		BypassHeaderValueHash: "sha1:abc",
+	}, "secret-hash-test"); err == nil {
+		t.Errorf("Expected an error for an unsupported hash")
+	}
+}